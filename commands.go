@@ -0,0 +1,223 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// command is a subcommand registered with WithCommand.
+type command struct {
+	name    string
+	help    string
+	cfg     any
+	options []ParseOption
+}
+
+// WithCommand returns a ParseOption that registers a subcommand with its
+// own config struct. When the first argument after the global flags names
+// the command, the arguments that follow it are parsed into cfg, while
+// the flags before it are parsed into the struct passed to
+// ParseWithOptions and shared by every command. The command's environment
+// variables take the prefix <PREFIX>_<COMMAND>.
+//
+// The name of the selected command is the first element of the global
+// conf.Args field, followed by the arguments left over from the command.
+// An argument that doesn't name a command is an error, while no argument
+// at all selects no command.
+//
+// The flags of the global struct are also accepted after the name of the
+// command, where they win over the same flags given before it, and the
+// command's help lists them under GLOBAL OPTIONS. A command flag with the
+// same name as a global flag hides the global one after the command.
+//
+// The command inherits the strict flags, strict bool flags, help and
+// version flags, all errors, dotenv and variable expansion options, and
+// the options passed here are added to them. Only the selected command's
+// struct is parsed. The struct may have no fields, or be nil, for a
+// command that takes none.
+//
+// Example:
+//
+//	var migrate struct {
+//		Steps int `conf:"default:1"`
+//	}
+//
+//	conf.ParseWithOptions("ADMIN", &cfg,
+//		conf.WithCommand("migrate", "run the database migrations", &migrate),
+//	)
+func WithCommand(name string, help string, cfg any, options ...ParseOption) ParseOption {
+	if cfg == nil {
+		cfg = &struct{}{}
+	}
+
+	return func(opts *parseOptions) {
+		opts.commands = append(opts.commands, command{
+			name:    name,
+			help:    help,
+			cfg:     cfg,
+			options: options,
+		})
+	}
+}
+
+// commandNames returns the names of the registered commands.
+func (opts *parseOptions) commandNames() []string {
+	names := make([]string, len(opts.commands))
+	for i, cmd := range opts.commands {
+		names[i] = cmd.name
+	}
+	return names
+}
+
+// dispatch parses the arguments that follow the name of a command into the
+// command's config struct. The flag arguments are replaced with the name
+// of the command and the arguments it left over, and the global flags
+// given after the name are added to the flags given before it.
+func (opts *parseOptions) dispatch(namespace string, flag *flag, cfgStruct any) error {
+	if len(opts.commands) == 0 || len(flag.args) == 0 {
+		return nil
+	}
+
+	var cmd *command
+	for i := range opts.commands {
+		if opts.commands[i].name == flag.args[0] {
+			cmd = &opts.commands[i]
+			break
+		}
+	}
+	if cmd == nil {
+		return fmt.Errorf("unknown command %q", flag.args[0])
+	}
+
+	cmdNamespace := cmd.namespace(namespace)
+	cmdOpts := cmd.parseOptions(opts)
+	cmdOpts.globals = opts.globalFields(cfgStruct)
+
+	globalSpec := opts.flagSpec(cfgStruct)
+	cmdSpec := cmdOpts.flagSpec(cmd.cfg)
+
+	cmdFlag, err := newSourceFlag(flag.args[1:], cmdSpec.merge(globalSpec))
+	switch {
+	case errors.Is(err, ErrHelpWanted):
+		return &commandHelpError{namespace: cmdNamespace, cfg: cmd.cfg, opts: cmdOpts}
+	case errors.Is(err, ErrVersionWanted):
+		return err
+	case err != nil:
+		return fmt.Errorf("command %s: %w", cmd.name, err)
+	}
+
+	moveGlobalFlags(flag, globalSpec, cmdFlag, cmdSpec)
+
+	err = parse(cmdFlag, cmdNamespace, cmd.cfg, cmdOpts)
+	flag.args = append([]string{cmd.name}, cmdFlag.args...)
+
+	var herr *commandHelpError
+	switch {
+	case errors.As(err, &herr), errors.Is(err, ErrVersionWanted):
+		return err
+	case err != nil:
+		return fmt.Errorf("command %s: %w", cmd.name, err)
+	}
+
+	return nil
+}
+
+// moveGlobalFlags moves the flags the command doesn't accept, but the
+// global struct does, from the command's flags to the global flags. They
+// were given after the name of the command, so they are placed after the
+// flags given before it.
+func moveGlobalFlags(global *flag, globalSpec flagSpec, cmd *flag, cmdSpec flagSpec) {
+	var next int
+	for _, vals := range global.m {
+		for _, val := range vals {
+			next = max(next, val.pos+1)
+		}
+	}
+
+	for name, vals := range cmd.m {
+		if _, exists := cmdSpec.lookup(name); exists {
+			continue
+		}
+		if _, exists := globalSpec.lookup(name); !exists {
+			continue
+		}
+
+		for _, val := range vals {
+			val.pos += next
+			global.m[name] = append(global.m[name], val)
+		}
+		delete(cmd.m, name)
+	}
+}
+
+// globalFields returns the fields of the global struct, along with the
+// reserved config flag, for the usage of a command.
+func (opts *parseOptions) globalFields(cfgStruct any) []Field {
+	fields, _ := extractFields(nil, cfgStruct)
+	if opts.configFile {
+		fields = append(fields, opts.configUsage())
+	}
+
+	sf := sortedFields{fields: fields}
+	sort.Sort(&sf)
+
+	return sf.fields
+}
+
+// namespace returns the namespace of the command's environment variables.
+func (cmd *command) namespace(parent string) string {
+	name := strings.ReplaceAll(cmd.name, "-", "_")
+	if parent == "" {
+		return name
+	}
+	return parent + "_" + name
+}
+
+// parseOptions returns the options for parsing the command, starting from
+// the ones it inherits from its parent.
+func (cmd *command) parseOptions(parent *parseOptions) *parseOptions {
+	opts := &parseOptions{
+		strictFlags:  parent.strictFlags,
+		strictBools:  parent.strictBools,
+		allErrors:    parent.allErrors,
+		dotenv:       parent.dotenv,
+		expandEnv:    parent.expandEnv,
+		expandStrict: parent.expandStrict,
+		command:      strings.TrimSpace(parent.command + " " + cmd.name),
+		help:         parent.help,
+		version:      parent.version,
+	}
+	for _, option := range cmd.options {
+		option(opts)
+	}
+	return opts
+}
+
+// commandHelpError reports that help was requested for a command. It
+// matches ErrHelpWanted with errors.Is.
+type commandHelpError struct {
+	namespace string
+	cfg       any
+	opts      *parseOptions
+}
+
+// Error implements the error interface.
+func (e *commandHelpError) Error() string {
+	return ErrHelpWanted.Error()
+}
+
+// Unwrap returns ErrHelpWanted.
+func (e *commandHelpError) Unwrap() error {
+	return ErrHelpWanted
+}
+
+// usage returns the usage information for the command.
+func (e *commandHelpError) usage() (string, error) {
+	fields, err := extractFields(nil, e.cfg)
+	if err != nil {
+		return "", err
+	}
+	return fmtUsage(e.namespace, fields, e.opts), nil
+}
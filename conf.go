@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"os"
 	"reflect"
+	"slices"
 	"sort"
 	"strings"
 )
@@ -25,10 +26,32 @@ type Parsers interface {
 	Process(prefix string, cfg any) error
 }
 
+// Namer is implemented by parsers and sources that want to be identified
+// by name in WithOrder. Parsers and sources without a name are called
+// "parser" and "source". When a name is used more than once, a numeric
+// suffix is added to each repeat, such as "yaml-2".
+type Namer interface {
+	Name() string
+}
+
 // parseOptions configures the behavior of the Parse function.
 type parseOptions struct {
-	strictFlags bool
-	parsers     []Parsers
+	strictFlags   bool
+	strictBools   bool
+	stages        []stage
+	order         []string
+	result        *Result
+	allErrors     bool
+	dotenv        []string
+	configFile    bool
+	configLoaders map[string]func(path string) Parsers
+	expandEnv     bool
+	expandStrict  bool
+	commands      []command
+	command       string  // the command being parsed, shown in usage
+	globals       []Field // the flags of the global struct, shown in a command's usage
+	help          builtinFlag
+	version       builtinFlag
 }
 
 // ParseOption defines a functional option for configuring Parse behavior.
@@ -43,15 +66,185 @@ func WithStrictFlags() ParseOption {
 	}
 }
 
+// WithStrictBoolFlags returns a ParseOption that stops bool flags from
+// taking the next argument as their value. By default a bool flag followed
+// by true or false takes it, so "--debug false" turns debug off. With this
+// option the value must be given with an equals sign, as in --debug=false,
+// and a following true or false is left in the arguments.
+func WithStrictBoolFlags() ParseOption {
+	return func(opts *parseOptions) {
+		opts.strictBools = true
+	}
+}
+
+// WithAllErrors returns a ParseOption that makes Parse check every field
+// before returning, instead of stopping at the first problem. The returned
+// error holds every problem found, one per line, and each of them can be
+// matched with errors.Is and errors.As.
+func WithAllErrors() ParseOption {
+	return func(opts *parseOptions) {
+		opts.allErrors = true
+	}
+}
+
 // WithParser returns a ParseOption that adds a custom parser to the parsing pipeline.
 // Parsers are executed in the order they are added, before environment variables
 // and command-line flags are processed.
 func WithParser(parser Parsers) ParseOption {
 	return func(opts *parseOptions) {
-		opts.parsers = append(opts.parsers, parser)
+		opts.addStage(stage{parser: parser}, parser, "parser")
+	}
+}
+
+// WithSource returns a ParseOption that adds a custom source to the parsing
+// pipeline. Unlike a parser, a source is asked for the value of each field
+// individually, so defaults, required, notzero and immutable are honored.
+// Sources are consulted in the order they are added, after the defaults are
+// applied and before environment variables and command-line flags.
+func WithSource(source Sourcer) ParseOption {
+	return func(opts *parseOptions) {
+		opts.addStage(stage{source: source}, source, "source")
+	}
+}
+
+// WithOrder returns a ParseOption that declares which sources are applied
+// and in what order, from lowest to highest precedence. Sources are named
+// with SourceDefault, SourceDotenv, SourceEnv, SourceFlag and the names of the
+// parsers and sources added with WithParser and WithSource (see Namer). Any
+// source that is not named is skipped entirely.
+//
+// Without this option the order is: all parsers, then defaults, then all
+// sources, then dotenv files, then environment variables, then command-line
+// flags. Defaults
+// only fill fields that are still set to their zero value, so they never
+// replace a value that an earlier source has provided.
+//
+// Only values from sources, environment variables and flags satisfy the
+// required tag. Defaults and parsers never do.
+//
+// Example, letting a configuration file win over the environment and
+// ignoring command-line flags:
+//
+//	conf.ParseWithOptions("APP", &cfg,
+//		conf.WithParser(yaml.WithData(data)),
+//		conf.WithOrder(conf.SourceDefault, conf.SourceEnv, "yaml"),
+//	)
+func WithOrder(names ...string) ParseOption {
+	return func(opts *parseOptions) {
+		opts.order = names
 	}
 }
 
+// addStage registers a parser or source under a unique name.
+func (opts *parseOptions) addStage(stg stage, v any, name string) {
+	if n, ok := v.(Namer); ok && n.Name() != "" {
+		name = n.Name()
+	}
+
+	count := 1
+	for _, existing := range opts.stages {
+		if existing.base == name {
+			count++
+		}
+	}
+
+	stg.base = name
+	stg.name = name
+	if count > 1 {
+		stg.name = fmt.Sprintf("%s-%d", name, count)
+	}
+
+	opts.stages = append(opts.stages, stg)
+}
+
+// pipeline returns the stages to execute, in the order they must run.
+func (opts *parseOptions) pipeline(namespace string, flag *flag, fields []Field) ([]stage, error) {
+	var files []stage
+	if len(opts.dotenv) > 0 {
+		dotenv, err := newSourceDotenv(namespace, opts.dotenv)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, stage{name: SourceDotenv, source: dotenv})
+	}
+
+	var config []stage
+	if opts.configFile {
+		stg, exists, err := opts.configStage(namespace, flag)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			config = append(config, stg)
+		}
+	}
+
+	// Only the real environment reads the files named by file tags, while
+	// any environment can name files with the _FILE suffix.
+	environ := newSourceEnv(namespace)
+	environ.tagFiles = true
+
+	known := make(map[string]bool)
+	for _, field := range fields {
+		known[strings.ToUpper(strings.ReplaceAll(strings.Join(field.EnvKey, `_`), `-`, `_`))] = true
+	}
+	for _, stg := range slices.Concat(files, config, []stage{{source: environ}}) {
+		if e, ok := stg.source.(*env); ok {
+			e.known = known
+		}
+	}
+
+	builtin := []stage{
+		{name: SourceDefault},
+		{name: SourceEnv, source: environ},
+		{name: SourceFlag, source: flag},
+	}
+
+	if len(opts.order) == 0 {
+		var stages []stage
+		for _, stg := range slices.Concat(opts.stages, config) {
+			if stg.parser != nil {
+				stages = append(stages, stg)
+			}
+		}
+		stages = append(stages, builtin[0])
+		for _, stg := range slices.Concat(opts.stages, files, config) {
+			if stg.source != nil {
+				stages = append(stages, stg)
+			}
+		}
+		return append(stages, builtin[1:]...), nil
+	}
+
+	available := make(map[string]stage)
+	for _, stg := range slices.Concat(builtin, files, config, opts.stages) {
+		available[stg.name] = stg
+	}
+
+	stages := make([]stage, 0, len(opts.order))
+	seen := make(map[string]bool, len(opts.order))
+	for _, name := range opts.order {
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate source %q in order", name)
+		}
+		seen[name] = true
+
+		stg, exists := available[name]
+		if !exists {
+
+			// The config file is optional, so there may be nothing to load.
+			if name == SourceConfig && opts.configFile {
+				continue
+			}
+			return nil, fmt.Errorf("unknown source %q in order", name)
+		}
+		delete(available, name)
+		stages = append(stages, stg)
+	}
+
+	return stages, nil
+}
+
 // =============================================================================
 
 // Parse parses the specified config struct. This function will
@@ -77,7 +270,18 @@ func Parse(prefix string, cfg any, parsers ...Parsers) (string, error) {
 //
 // Options can be provided to customize parsing behavior:
 //   - conf.WithStrictFlags(): Return an error for unrecognized command-line flags
+//   - conf.WithStrictBoolFlags(): Only take bool flag values given with an equals sign
+//   - conf.WithHelpFlag(name, short): Rename the help flag, such as to free -h
+//   - conf.WithVersionFlag(name, short): Rename the version flag, such as to free -v
 //   - conf.WithParser(parser): Add a custom parser to the parsing pipeline
+//   - conf.WithSource(source): Add a custom per-field source to the parsing pipeline
+//   - conf.WithDotenv(paths...): Read environment variables from dotenv files
+//   - conf.WithConfigFile(loaders): Load the file named by --config or <PREFIX>_CONFIG
+//   - conf.WithExpandEnv(): Expand ${VAR} in defaults and parser values
+//   - conf.WithCommand(name, help, &cmdCfg): Register a subcommand with its own config
+//   - conf.WithOrder(names...): Choose which sources apply and their precedence
+//   - conf.WithResult(&res): Report which source set each field
+//   - conf.WithAllErrors(): Report every problem instead of just the first
 //
 // Example:
 //
@@ -94,21 +298,25 @@ func ParseWithOptions(prefix string, cfg any, options ...ParseOption) (string, e
 		option(opts)
 	}
 
-	// Process parsers from options
-	for _, parser := range opts.parsers {
-		if err := parser.Process(prefix, cfg); err != nil {
-			return "", fmt.Errorf("external parser: %w", err)
-		}
+	flag, err := newSourceFlag(args, opts.flagSpec(cfg))
+	if err == nil {
+		err = parse(flag, prefix, cfg, opts)
 	}
-
-	err := parse(args, prefix, cfg, opts)
 	if err == nil {
 		return "", nil
 	}
 
+	var herr *commandHelpError
 	switch {
+	case errors.As(err, &herr):
+		usage, err := herr.usage()
+		if err != nil {
+			return "", fmt.Errorf("generating config usage: %w", err)
+		}
+		return usage, ErrHelpWanted
+
 	case errors.Is(err, ErrHelpWanted):
-		usage, err := UsageInfo(prefix, cfg)
+		usage, err := UsageInfo(prefix, cfg, options...)
 		if err != nil {
 			return "", fmt.Errorf("generating config usage: %w", err)
 		}
@@ -181,13 +389,19 @@ func maskVal(v string) string {
 }
 
 // UsageInfo provides output to display the config usage on the command line.
-func UsageInfo(namespace string, v any) (string, error) {
+// Options that reserve flags, such as WithConfigFile, add them to the output.
+func UsageInfo(namespace string, v any, options ...ParseOption) (string, error) {
 	fields, err := extractFields(nil, v)
 	if err != nil {
 		return "", err
 	}
 
-	return fmtUsage(namespace, fields), nil
+	opts := &parseOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+
+	return fmtUsage(namespace, fields, opts), nil
 }
 
 // VersionInfo provides output to display the application version and description on the command line.
@@ -220,14 +434,21 @@ func VersionInfo(namespace string, v any) (string, error) {
 // =============================================================================
 
 // parse parses configuration into the provided struct.
-func parse(args []string, namespace string, cfgStruct any, opts *parseOptions) error {
+func parse(flag *flag, namespace string, cfgStruct any, opts *parseOptions) error {
+	if opts == nil {
+		opts = &parseOptions{}
+	}
 
-	// Create the flag and env sources.
-	flag, err := newSourceFlag(args)
-	if err != nil {
-		return err
+	// A command parses the arguments that follow its name into its own
+	// config struct, so only its name and leftover args remain here.
+	var cmdErr error
+	if err := opts.dispatch(namespace, flag, cfgStruct); err != nil {
+		var herr *commandHelpError
+		if !opts.allErrors || errors.As(err, &herr) || errors.Is(err, ErrVersionWanted) {
+			return err
+		}
+		cmdErr = err
 	}
-	sources := []sourcer{newSourceEnv(namespace), flag}
 
 	// Get the list of fields from the configuration struct to process.
 	fields, err := extractFields(nil, cfgStruct)
@@ -235,93 +456,166 @@ func parse(args []string, namespace string, cfgStruct any, opts *parseOptions) e
 		return err
 	}
 
-	if len(fields) == 0 {
+	// Commands and the structs that hold them may have no fields of their
+	// own, since the arguments can be all they need.
+	if len(fields) == 0 && opts.command == "" && len(opts.commands) == 0 {
 		return errors.New("no fields identified in config struct")
 	}
 
-	// Hold the field the is supposed to hold the leftover args.
-	var argsF *Field
+	if opts.configFile {
+		if err := checkConfigConflict(namespace, fields); err != nil {
+			return err
+		}
+	}
 
-	// Process all fields found in the config struct provided.
-	for _, field := range fields {
+	stages, err := opts.pipeline(namespace, flag, fields)
+	if err != nil {
+		return err
+	}
 
-		// Do not change version fields Build and Description
-		if field.Name == buildKey || field.Name == descKey {
-			continue
-		}
+	// Track the stages that provided a value for each field.
+	orgs := make(origins)
 
-		// If the field is supposed to hold the leftover args then hold a reference for later.
-		if field.Field.Type() == argsT {
-			argsF = &field
-			continue
-		}
+	// Gather the problems found along the way.
+	errs := newCollector(opts.allErrors)
+	if cmdErr != nil {
+		errs.report(cmdErr)
+	}
+
+	// Track the defaults that refer to other fields.
+	pending := make(map[string]bool)
 
-		// Set any default value into the struct for this field.
-		if field.Options.DefaultVal != "" {
-			if err := processField(true, field.Options.DefaultVal, field.Field); err != nil {
-				return &FieldError{
-					fieldName: field.Name,
-					typeName:  field.Field.Type().String(),
-					value:     field.Options.DefaultVal,
-					err:       err,
+	// Apply each stage to the config struct in order of precedence.
+	for _, stg := range stages {
+		switch {
+		case stg.parser != nil:
+			before := snapshot(fields)
+
+			if err := stg.parser.Process(namespace, cfgStruct); err != nil {
+				if err := errs.report(fmt.Errorf("external parser: %w", err)); err != nil {
+					return err
 				}
 			}
-			if field.mapParent.IsValid() {
-				field.mapParent.SetMapIndex(field.mapKey, field.Field)
-			}
-		}
 
-		// If this is an immutable field then don't let it
-		// be overridden.
-		if field.Options.Immutable {
-			continue
-		}
+			// The parser may have populated maps, so pick up their entries.
+			if fields, err = extractFields(nil, cfgStruct); err != nil {
+				return err
+			}
 
-		// Flag to check if an override value is provided.
-		foundOverride := false
+			// The parser doesn't report what it set, so look for changes.
+			for _, field := range fields {
+				if value, exists := before[field.key]; !exists || value != fieldString(field.Field) {
+					orgs.add(field, stg.name, true, false)
 
-		// Process each field against all sources.
-		for _, sourcer := range sources {
-			if sourcer == nil {
-				continue
+					if err := opts.expandField(field); err != nil {
+						if err := errs.reportField(field, fmt.Errorf("expanding field %s: %w", field.Name, err)); err != nil {
+							return err
+						}
+					}
+				}
 			}
 
-			value, ok := sourcer.Source(field)
-			if !ok {
-				continue
+		case stg.source == nil:
+			if err := applyDefaults(namespace, fields, orgs, errs, opts, pending); err != nil {
+				return err
 			}
 
-			// A override was found so update the struct value with it.
-			if err := processField(false, value, field.Field); err != nil {
-				return &FieldError{
-					fieldName: field.Name,
-					typeName:  field.Field.Type().String(),
-					value:     value,
-					err:       err,
+		default:
+			if loader, ok := stg.source.(Loader); ok {
+				if err := loader.Load(); err != nil {
+					if err := errs.report(fmt.Errorf("loading source %s: %w", stg.name, err)); err != nil {
+						return err
+					}
+					continue
 				}
 			}
-			if field.mapParent.IsValid() {
-				field.mapParent.SetMapIndex(field.mapKey, field.Field)
+
+			if err := applySource(namespace, stg, fields, orgs, errs); err != nil {
+				return err
 			}
+		}
+	}
+
+	// Set the defaults that refer to other fields now that they are final.
+	if len(pending) > 0 {
+		if err := resolveDefaults(namespace, fields, orgs, errs, opts, pending); err != nil {
+			return err
+		}
+	}
+
+	// Hold the field the is supposed to hold the leftover args.
+	var argsF *Field
+
+	// Validate the final value of every field.
+	for _, field := range fields {
 
-			foundOverride = true
+		// If the field is supposed to hold the leftover args then hold a reference for later.
+		if field.Field.Type() == argsT {
+			argsF = &field
+			continue
+		}
+
+		// Fields that failed to take a value have already been reported.
+		if skipField(field) || errs.failed[field.key] {
+			continue
 		}
 
 		if field.Options.NotZero && field.Field.IsZero() {
-			envSuffix := ""
-			if field.Options.EnvName != "" {
-				envSuffix = fmt.Sprintf(" (env: %s)", field.Options.EnvName)
+			zerr := &ZeroValueError{
+				Field:   field.Name,
+				Key:     field.key,
+				EnvKey:  envUsage(namespace, field),
+				FlagKey: strings.ToLower(strings.Join(field.FlagKey, `-`)),
+				Source:  orgs.source(field),
+				envName: field.Options.EnvName,
+			}
+			if err := errs.report(zerr); err != nil {
+				return err
 			}
-			return fmt.Errorf("field %s%s is set to zero value", field.Name, envSuffix)
+			continue
 		}
 
 		// If the field is marked 'required', check if no value was provided.
-		if field.Options.Required && !foundOverride {
-			envSuffix := ""
-			if field.Options.EnvName != "" {
-				envSuffix = fmt.Sprintf(" (env: %s)", field.Options.EnvName)
+		if field.Options.Required && !orgs.provided(field) {
+			rerr := &RequiredError{
+				Field:   field.Name,
+				Key:     field.key,
+				EnvKey:  envUsage(namespace, field),
+				FlagKey: strings.ToLower(strings.Join(field.FlagKey, `-`)),
+				envName: field.Options.EnvName,
+			}
+			if err := errs.report(rerr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// A zero value no stage set was never given to the program, so
+		// there is nothing to check. Use required to demand a value.
+		if orgs.source(field) == "" && field.Field.IsZero() {
+			continue
+		}
+
+		// Check the final value against the validation tags.
+		if rule, param, reason := validate(field); rule != "" {
+			value := fieldString(field.Field)
+			if field.Options.Mask || field.Options.Noprint {
+				value = maskVal(value)
+			}
+			verr := &ValidationError{
+				Field:   field.Name,
+				Key:     field.key,
+				EnvKey:  envUsage(namespace, field),
+				FlagKey: strings.ToLower(strings.Join(field.FlagKey, `-`)),
+				Source:  orgs.source(field),
+				Value:   value,
+				Rule:    rule,
+				Param:   param,
+				reason:  reason,
+			}
+			if err := errs.report(verr); err != nil {
+				return err
 			}
-			return fmt.Errorf("required field %s%s is missing value", field.Name, envSuffix)
 		}
 	}
 
@@ -332,18 +626,216 @@ func parse(args []string, namespace string, cfgStruct any, opts *parseOptions) e
 		argsF.Field.Set(args)
 	}
 
+	// Once every field holds a valid value, run the rules that involve
+	// more than one field.
+	if len(errs.errs) == 0 {
+		if err := runValidators(cfgStruct, errs); err != nil {
+			return err
+		}
+	}
+
 	// If strict flag mode is enabled, check for unconsumed flags.
-	if opts != nil && opts.strictFlags {
+	if opts.strictFlags {
 		if unconsumed := flag.unconsumedFlags(); len(unconsumed) > 0 {
 			// Sort for consistent error messages
 			sort.Strings(unconsumed)
+
+			err := fmt.Errorf("unrecognized flags: --%s", strings.Join(unconsumed, ", --"))
 			if len(unconsumed) == 1 {
-				return fmt.Errorf("unrecognized flag: --%s", unconsumed[0])
+				err = fmt.Errorf("unrecognized flag: --%s", unconsumed[0])
+			}
+			if err := errs.report(err); err != nil {
+				return err
 			}
-			return fmt.Errorf("unrecognized flags: --%s", strings.Join(unconsumed, ", --"))
 		}
 	}
 
+	if err := errs.err(); err != nil {
+		return err
+	}
+
+	if opts.result != nil {
+		orgs.record(opts.result, namespace, fields)
+	}
+
+	return nil
+}
+
+// skipField reports whether the field is outside the control of the
+// sources, such as the version fields and the leftover args.
+func skipField(field Field) bool {
+	return field.Name == buildKey || field.Name == descKey || field.Field.Type() == argsT
+}
+
+// applyDefaults sets the default value of every field that is still set to
+// its zero value. Defaults that refer to other fields are added to pending
+// instead, to be set by resolveDefaults.
+func applyDefaults(namespace string, fields []Field, orgs origins, errs *collector, opts *parseOptions, pending map[string]bool) error {
+	byKey := make(map[string]Field, len(fields))
+	for _, field := range fields {
+		byKey[field.key] = field
+	}
+
+	for _, field := range fields {
+		if skipField(field) || field.Options.DefaultVal == "" {
+			continue
+		}
+
+		// We don't want a default value to override a proper setting.
+		if !isZeroValue(field.Field) {
+			orgs.add(field, SourceDefault, false, false)
+			continue
+		}
+
+		// A default that refers to other fields must see their final
+		// values, so it waits until every source has been applied.
+		if slices.ContainsFunc(references(field.Options.DefaultVal), func(name string) bool {
+			_, exists := byKey[name]
+			return exists
+		}) {
+			pending[field.key] = true
+			continue
+		}
+
+		if err := setDefault(namespace, field, opts.expandString, orgs, errs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveDefaults sets the pending defaults that refer to other fields,
+// once every source has been applied. A default that refers to a field
+// with a pending default is set after it, and a cycle is an error.
+func resolveDefaults(namespace string, fields []Field, orgs origins, errs *collector, opts *parseOptions, pending map[string]bool) error {
+	byKey := make(map[string]Field, len(fields))
+	for _, field := range fields {
+		byKey[field.key] = field
+	}
+
+	expand := func(s string) (string, error) {
+		return opts.expandDefault(s, byKey)
+	}
+
+	const (
+		visiting = 1
+		resolved = 2
+	)
+	state := make(map[string]int)
+
+	var resolve func(path []string) error
+	resolve = func(path []string) error {
+		key := path[len(path)-1]
+
+		switch state[key] {
+		case resolved:
+			return nil
+		case visiting:
+			start := slices.Index(path, key)
+			return fmt.Errorf("cycle in default references: %s", strings.Join(path[start:], " -> "))
+		}
+		state[key] = visiting
+
+		field, exists := byKey[key]
+		if !exists {
+			state[key] = resolved
+			return nil
+		}
+
+		for _, name := range references(field.Options.DefaultVal) {
+			if pending[name] {
+				if err := resolve(append(slices.Clip(path), name)); err != nil {
+					return err
+				}
+			}
+		}
+		state[key] = resolved
+
+		// A source may have set the field after the defaults were applied.
+		if !isZeroValue(field.Field) {
+			orgs.add(field, SourceDefault, false, false)
+			return nil
+		}
+
+		return setDefault(namespace, field, expand, orgs, errs)
+	}
+
+	for _, field := range fields {
+		if pending[field.key] {
+			if err := resolve([]string{field.key}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// setDefault sets the field to its default value, once the references in
+// it are expanded.
+func setDefault(namespace string, field Field, expand func(string) (string, error), orgs origins, errs *collector) error {
+	value, err := expand(field.Options.DefaultVal)
+	if err != nil {
+		return errs.reportField(field, fmt.Errorf("expanding default for field %s: %w", field.Name, err))
+	}
+
+	if err := processField(true, value, field.Field); err != nil {
+		return errs.reportField(field, newFieldError(namespace, field, SourceDefault, value, err))
+	}
+	if field.mapParent.IsValid() {
+		field.mapParent.SetMapIndex(field.mapKey, field.Field)
+	}
+
+	orgs.add(field, SourceDefault, true, false)
+	return nil
+}
+
+// applySource overrides every field the stage's source has a value for,
+// recording the fields it provided.
+func applySource(namespace string, stg stage, fields []Field, orgs origins, errs *collector) error {
+	for _, field := range fields {
+
+		// If this is an immutable field then don't let it
+		// be overridden.
+		if skipField(field) || field.Options.Immutable {
+			continue
+		}
+
+		value, ok, err := stg.source.Source(field)
+		if err != nil {
+			if err := errs.reportField(field, fmt.Errorf("sourcing field %s: %w", field.Name, err)); err != nil {
+				return err
+			}
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		// A override was found so update the struct value with it. Slices
+		// and maps take every value of a repeated flag.
+		if f, ok := stg.source.(*flag); ok && repeatable(field.Field) {
+			values, _ := f.sourceAll(field)
+			value = strings.Join(values, ";")
+			err = processValues(values, field.Field)
+		} else {
+			err = processField(false, value, field.Field)
+		}
+		if err != nil {
+			ferr := newFieldError(namespace, field, stg.name, value, err)
+			if err := errs.reportField(field, ferr); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.mapParent.IsValid() {
+			field.mapParent.SetMapIndex(field.mapKey, field.Field)
+		}
+
+		orgs.add(field, stg.name, true, true)
+	}
+
 	return nil
 }
 
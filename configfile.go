@@ -0,0 +1,167 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// SourceConfig is the name of the config file source, for use with
+// WithOrder.
+const SourceConfig = "config"
+
+// Names reserved for the config file option.
+const (
+	configKey   = "config"
+	configShort = 'c'
+)
+
+// WithConfigFile returns a ParseOption that reserves the --config and -c
+// flags and the <PREFIX>_CONFIG environment variable for the path to a
+// configuration file, with the flag taking precedence. The format is
+// chosen by the file extension, such as .yaml, which is looked up in the
+// loaders to construct the parser for the file. Files with the .env
+// extension are always supported and read like WithDotenv.
+//
+// Parsers are applied after any other parsers, and env files after any
+// other dotenv files, so the file is always loaded before environment
+// variables and flags. It is an error for the file not to exist or for
+// its extension to have no loader. When neither the flag nor the variable
+// is set nothing is loaded.
+//
+// Example:
+//
+//	conf.WithConfigFile(map[string]func(path string) conf.Parsers{
+//		".yaml": func(path string) conf.Parsers { return yaml.WithFile(path) },
+//		".json": func(path string) conf.Parsers { return json.WithFile(path) },
+//	})
+func WithConfigFile(loaders map[string]func(path string) Parsers) ParseOption {
+	return func(opts *parseOptions) {
+		opts.configFile = true
+		opts.configLoaders = make(map[string]func(path string) Parsers, len(loaders))
+		for ext, loader := range loaders {
+			opts.configLoaders[strings.ToLower(ext)] = loader
+		}
+	}
+}
+
+// configStage returns the stage that loads the config file named by the
+// --config flag or the <PREFIX>_CONFIG variable, if either is set.
+func (opts *parseOptions) configStage(namespace string, flag *flag) (stage, bool, error) {
+	path, exists, err := configPath(namespace, flag)
+	if err != nil || !exists {
+		return stage{}, false, err
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return stage{}, false, fmt.Errorf("config file %s does not exist", path)
+		}
+		return stage{}, false, fmt.Errorf("config file: %w", err)
+	}
+
+	stg := stage{name: SourceConfig, base: SourceConfig}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	loader, exists := opts.configLoaders[ext]
+	switch {
+	case exists:
+		stg.parser = loader(path)
+	case ext == ".env":
+		dotenv, err := newSourceDotenv(namespace, []string{path})
+		if err != nil {
+			return stage{}, false, err
+		}
+		stg.source = dotenv
+	default:
+		return stage{}, false, fmt.Errorf("config file %s: unsupported extension %q", path, ext)
+	}
+
+	return stg, true, nil
+}
+
+// configPath returns the path to the config file, looking at the flags
+// before the environment.
+func configPath(namespace string, flag *flag) (string, bool, error) {
+	for _, name := range []string{configKey, string(configShort)} {
+		if _, exists := flag.m[name]; !exists {
+			continue
+		}
+
+		path, _ := flag.source(name, false)
+		if path == "" {
+			return "", false, fmt.Errorf("flag --%s requires a path", configKey)
+		}
+		return path, true, nil
+	}
+
+	if path, exists := os.LookupEnv(envUsage(namespace, configField())); exists && path != "" {
+		return path, true, nil
+	}
+
+	return "", false, nil
+}
+
+// configField describes the reserved config flag for usage and for
+// detecting conflicts with the fields of the config struct.
+func configField() Field {
+	return Field{
+		Name:    configKey,
+		Field:   reflect.ValueOf(""),
+		FlagKey: []string{configKey},
+		EnvKey:  []string{strings.ToUpper(configKey)},
+		Options: FieldOptions{
+			ShortFlagChar: configShort,
+			Help:          "load configuration from a 'file'",
+		},
+	}
+}
+
+// configUsage describes the reserved config flag for usage, listing the
+// supported extensions in its help.
+func (opts *parseOptions) configUsage() Field {
+	exts := []string{".env"}
+	for ext := range opts.configLoaders {
+		if ext != ".env" {
+			exts = append(exts, ext)
+		}
+	}
+	slices.Sort(exts)
+
+	list := exts[0]
+	if n := len(exts); n > 1 {
+		list = strings.Join(exts[:n-1], ", ") + " or " + exts[n-1]
+	}
+
+	field := configField()
+	field.Options.Help = "load configuration from a " + list + " 'file'"
+	return field
+}
+
+// checkConfigConflict reports a field that uses a name reserved by
+// WithConfigFile.
+func checkConfigConflict(namespace string, fields []Field) error {
+	reserved := configField()
+
+	for _, field := range fields {
+		if skipField(field) {
+			continue
+		}
+
+		switch {
+		case strings.EqualFold(strings.Join(field.FlagKey, "-"), configKey):
+			return fmt.Errorf("field %s: flag --%s is reserved for the config file", field.Name, configKey)
+		case field.Options.ShortFlagChar == configShort:
+			return fmt.Errorf("field %s: flag -%c is reserved for the config file", field.Name, configShort)
+		case envUsage(namespace, field) == envUsage(namespace, reserved):
+			return fmt.Errorf("field %s: env %s is reserved for the config file", field.Name, envUsage(namespace, reserved))
+		}
+	}
+
+	return nil
+}
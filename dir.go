@@ -0,0 +1,72 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DirSource is a source for a directory holding one file per value, such as
+// a Kubernetes ConfigMap or Secret mounted as a volume. A field is read from
+// the file named by its flag key, such as web-api-host, or by its env key
+// without the namespace, such as WEB_API_HOST. A trailing newline is removed
+// from the value.
+type DirSource struct {
+	path string
+}
+
+// NewDirSource constructs a source for the specified directory, to be added
+// with WithSource.
+func NewDirSource(path string) DirSource {
+	return DirSource{
+		path: path,
+	}
+}
+
+// Name implements the Namer interface.
+func (d DirSource) Name() string {
+	return "dir"
+}
+
+// Files implements the FileBacked interface. Kubernetes updates a mounted
+// volume by swapping the ..data symlink, so watching it picks up every
+// change at once. The directory itself is watched for files being added or
+// removed when it isn't a mounted volume.
+func (d DirSource) Files() []string {
+	return []string{d.path, filepath.Join(d.path, "..data")}
+}
+
+// Source implements the Sourcer interface. It returns the contents of the
+// file named after the field.
+func (d DirSource) Source(fld Field) (string, bool, error) {
+	names := []string{
+		strings.ToLower(strings.Join(fld.FlagKey, `-`)),
+		strings.ToUpper(strings.ReplaceAll(strings.Join(fld.EnvKey, `_`), `-`, `_`)),
+	}
+
+	for _, name := range names {
+		path := filepath.Join(d.path, name)
+
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("stat file: %w", err)
+		}
+		if info.IsDir() {
+			continue
+		}
+
+		v, err := readSecretFile(path)
+		if err != nil {
+			return "", false, err
+		}
+		return v, true, nil
+	}
+
+	return "", false, nil
+}
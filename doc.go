@@ -18,13 +18,95 @@ to customize the flag options.
 	short    - Denotes a shorthand option for the flag.
 	noprint  - Denotes to not include the field in any display string.
 	mask     - Includes the field in any display string but masks out the value.
+	           Values that fail to convert are masked in errors for both tags, and
+	           the conversion error is replaced with a generic one.
 	required - Denotes a overriding value must be provided using a flag or env variable.
 	notzero  - Denotes a field can't be set to its zero value.
 	help     - Provides a description for the help.
+	file     - Reads the value from a file when the environment variable isn't set.
+	count    - Sets an integer to the number of times the flag is given.
+
+These tags validate the final value of a field, once every source has been
+applied. A failure is reported as a ValidationError. A nil pointer, or a zero
+value that no default or source set, isn't checked; use required to demand a
+value.
+
+	min      - The lowest allowed number or duration, such as min:1.
+	max      - The highest allowed number or duration, such as max:65535.
+	minlen   - The shortest allowed string, slice or map, such as minlen:3.
+	maxlen   - The longest allowed string, slice or map, such as maxlen:64.
+	oneof    - The allowed values separated by |, such as oneof:debug|info|warn.
+	           For slices every element must be one of the values.
+	pattern  - A regular expression a string must match, such as pattern:^[a-z]+$.
+	           The expression can't contain a comma.
+
+Rules that involve more than one field belong in a Validate method. Once
+every field has a valid value, Parse calls the Validate method of each nested
+struct, deepest first, and then of the config struct itself. Errors from a
+nested struct are prefixed with its key path, such as "Web.TLS: ...". The
+method of an embedded struct is promoted, so it runs as part of the struct
+that embeds it. When the struct declares its own Validate method, which
+shadows the embedded one, both are called.
+
+	type TLS struct {
+		Cert string
+		Key  string
+	}
+
+	func (t TLS) Validate() error {
+		if t.Cert != "" && t.Key == "" {
+			return errors.New("cert requires key")
+		}
+		return nil
+	}
 
 The field name and any parent struct name will be used for the long form of
 the command name unless the name is overridden.
 
+Slices and maps are set from a single value with the elements separated by
+a semicolon, such as --tags=a;b or --labels=env:prod;tier:web. A flag can
+also be repeated, and then every value is one element, so a value may
+contain a semicolon. A repeated or single flag for a map takes a key=value
+pair. Any other flag given more than once keeps its last value.
+
+	$ my-program --tags a --tags "b;c" --labels env=prod --labels tier=web
+
+Flags are read until the first argument that isn't one, or until "--".
+These forms are accepted, where x, y and p are short flags set with the
+short tag, x and y are bools and p takes a value:
+
+	--name value, --name=value  long flag
+	-name value, -name=value    long flag with a single dash
+	-p value, -p=value          short flag
+	-xy                         combined bool short flags, same as -x -y
+	-p8080, -p=8080             short flag with an attached value
+	-xyp8080, -xyp 8080         bools combined with a flag that takes a value
+
+A single dash followed by more than one character is a long flag when it
+names one, so -version and -port=80 keep working. Otherwise, when it starts
+with a short flag, the characters are short flags. They are read as bools
+up to the first flag that takes a value, which takes the rest of the
+argument, or the next argument when nothing is left.
+
+A bool flag is turned on by its name alone and turned off by its name with
+a no- prefix, so a bool that defaults to true is turned off with
+--no-debug. When both are given the last one wins. By default a bool flag
+also takes a following true or false argument as its value, as in
+"--debug false". WithStrictBoolFlags turns that off, so the value must be
+given as --debug=false and a following true or false stays an argument.
+
+A field tagged with count counts its flag instead of taking a value, so
+-vvv, -v -v -v and --verbose --verbose --verbose all set it to 3, while
+--verbose=2 counts as two. The environment variable still sets the number.
+The built-in flags -v and -h belong to version and help, and can be renamed
+or left out with WithVersionFlag and WithHelpFlag to free them.
+
+	var cfg struct {
+		Verbose int `conf:"count,short:v"`
+	}
+
+	conf.ParseWithOptions("APP", &cfg, conf.WithVersionFlag("version", 0))
+
 # Example Usage
 
 As an example, using "APP" prefix and this config struct:
@@ -51,15 +133,15 @@ The following usage information would be output you can display.
 Usage: conf.test [options...] [arguments...]
 
 OPTIONS
-  -a, --a-string  <string>,[string...]  (default: A;B;C)        slice example
-      --an-int    <value>               (default: min:0;max:9)  map example
-      --bool      <bool>
-  -d, --e-dur     <duration>            (default: 1s)
-  -h, --help                                                    display this help message
-      --ip-ip     <string>              (default: 127.0.0.0)
-      --ip-name   <string>              (default: localhost)
-      --name      <string>              (default: bill)
-  -v, --version                                                 display version
+  -a, --a-string   <string>,[string...]  (default: A;B;C)        slice example
+      --an-int     <value>               (default: min:0;max:9)  map example
+      --[no-]bool  <bool>
+  -d, --e-dur      <duration>            (default: 1s)
+  -h, --help                                                     display this help message
+      --ip-ip      <string>              (default: 127.0.0.0)
+      --ip-name    <string>              (default: localhost)
+      --name       <string>              (default: bill)
+  -v, --version                                                  display version
 
 ENVIRONMENT
   APP_A_STRING  <string>,[string...]  (default: A;B;C)        slice example
@@ -70,6 +152,8 @@ ENVIRONMENT
   APP_IP_NAME   <string>              (default: localhost)
   APP_NAME      <string>              (default: bill)
 
+  Add the _FILE suffix to any variable to read its value from a file.
+
 # Example Parsing
 
 There is an API called Parse that can process a config struct with environment
@@ -86,6 +170,11 @@ variable and command line flag overrides.
 		return fmt.Errorf("parsing config: %w", err)
 	}
 
+By default Parse stops at the first problem it finds. Use WithAllErrors to have
+every field checked, so all the problems are reported together, one per line.
+
+	help, err := conf.ParseWithOptions(prefix, &cfg, conf.WithAllErrors())
+
 There is also YAML support using the yaml package that is part of
 this module.
 
@@ -119,6 +208,206 @@ this module.
 There is a WithReader function that takes any concrete value that knows how to
 Read (io.Reader).
 
+Several yaml documents, such as a base file and environment overlays, can be
+deep-merged with WithLayers before they are applied. Mappings are merged key
+by key, scalars and sequences replace earlier values and an explicit null
+removes a key so the field falls back to its default, while a document that
+is only a null changes nothing. Use AppendSlices to have sequences appended
+instead.
+
+	parser := yaml.WithLayers(
+		yaml.WithFile("base.yaml"),
+		yaml.WithFile("prod.yaml"),
+		yaml.WithFile("prod-eu.yaml"),
+	)
+	help, err := conf.Parse(prefix, &cfg, parser)
+
+JSON is supported the same way by the json package, which only depends on the
+standard library. Use WithStrict to reject keys that don't match a field, and
+errors report the line and column of the offending value.
+
+	help, err := conf.Parse(prefix, &cfg, json.WithFile("config.json", json.WithStrict()))
+
+TOML is supported by the toml package. Tables map to nested structs and keys
+are matched to field names ignoring case, or renamed with a toml tag.
+
+	help, err := conf.Parse(prefix, &cfg, toml.WithFile("config.toml"))
+
+# Secret Files
+
+Secrets mounted as files, such as Docker and Kubernetes secrets, can be read
+by setting the environment variable of a field with a _FILE suffix to the path
+of the file. A trailing newline is removed. Setting both the variable and the
+_FILE variable is an error. A file tag names a fixed path to read when neither
+is set, and is skipped if the file doesn't exist. Values read from files count
+as provided for the required tag.
+
+	$ export APP_DB_PASSWORD_FILE=/run/secrets/db
+
+	type config struct {
+		DB struct {
+			Password string `conf:"required,mask"`
+			APIKey   string `conf:"mask,file:/run/secrets/api-key"`
+		}
+	}
+
+# Dotenv Files
+
+WithDotenv reads variables from one or more dotenv files. They are matched to
+fields with the same prefix and names as environment variables, and the real
+environment wins when a variable is set in both. Lines may start with export,
+comments start with # at the start of a line or after a space or tab, and
+quoted values may span lines. Escapes such as \n are only replaced inside
+double quotes.
+
+	# .env
+	export APP_WEB_API_HOST=0.0.0.0:4000
+	APP_DB_PASSWORD='p@ss#word'
+	APP_MOTD="welcome
+	to the app"
+
+	help, err := conf.ParseWithOptions(prefix, &cfg, conf.WithDotenv(".env"))
+
+# Config Files
+
+WithConfigFile reserves the --config and -c flags and the <PREFIX>_CONFIG
+environment variable for the path to a configuration file, and shows them in
+the usage output. The format is chosen by the extension, which is looked up in
+the loaders passed to WithConfigFile, so only the formats a program registers
+are compiled into it. Files with the .env extension are always supported. The
+file is loaded after any other parsers or dotenv files and before environment
+variables and flags. A path to a file that doesn't exist, or whose extension
+has no loader, is an error.
+
+	loaders := map[string]func(path string) conf.Parsers{
+		".yaml": func(path string) conf.Parsers { return yaml.WithFile(path) },
+		".toml": func(path string) conf.Parsers { return toml.WithFile(path) },
+	}
+
+	help, err := conf.ParseWithOptions(prefix, &cfg, conf.WithConfigFile(loaders))
+
+	$ my-program --config=/etc/app/config.yaml
+
+# Variable Expansion
+
+With WithExpandEnv, references to environment variables are expanded in
+default tags and in the strings set by parsers such as yaml. ${VAR} is
+replaced by the value of VAR and ${VAR:-fallback} uses the fallback when VAR
+is unset or empty. Write $${ for a literal ${. WithStrictExpandEnv makes a
+reference to an unset variable without a fallback an error. Values from
+environment variables and flags are never expanded.
+
+	type config struct {
+		CacheDir string `conf:"default:${HOME}/.cache/app"`
+		DSN      string // dsn: postgres://${DB_USER}@db/${DB_NAME:-app}
+	}
+
+	help, err := conf.ParseWithOptions(prefix, &cfg, conf.WithExpandEnv())
+
+A default tag can refer to another field by its key path, with or without
+WithExpandEnv. These defaults are set after every source has been applied, so
+they see the final value of the field they refer to, and they are set in
+dependency order. A cycle of references is an error.
+
+	type config struct {
+		Web struct {
+			Host      string `conf:"default:0.0.0.0"`
+			APIHost   string `conf:"default:${Web.Host}:3000"`
+			DebugHost string `conf:"default:${Web.Host}:4000"`
+		}
+	}
+
+# Custom Sources
+
+A parser fills the whole struct in one pass, before defaults are applied. When
+values live in a store that can be queried per field (a secret store, a set of
+files, a database), implement the Sourcer interface instead and register it
+with WithSource. Sources are asked for each field in turn, after defaults and
+before environment variables and flags, so required, notzero and immutable
+apply to the values they provide.
+
+	type secrets map[string]string
+
+	func (s secrets) Source(fld conf.Field) (string, bool, error) {
+		v, ok := s[strings.Join(fld.FlagKey, "-")]
+		return v, ok, nil
+	}
+
+	help, err := conf.ParseWithOptions(prefix, &cfg, conf.WithSource(secrets{...}))
+
+NewDirSource returns a source for a directory with one file per value, such as
+a Kubernetes ConfigMap or Secret mounted as a volume. Each field is read from
+the file named by its flag key (web-api-host) or its env key without the
+prefix (WEB_API_HOST). A Reloader watching the source reloads when Kubernetes
+swaps the ..data symlink to publish new values.
+
+	help, err := conf.ParseWithOptions(prefix, &cfg, conf.WithSource(conf.NewDirSource("/etc/app")))
+
+A source that fetches all of its data at once can implement Loader. Its Load
+method is called at the start of every parse, before any field is sourced. The
+httpkv package uses it to fetch the keys under a prefix from a Consul style
+key/value service. The httpkvtest package provides a test server to use in
+place of the real service.
+
+	src := httpkv.New("http://consul:8500", "app", httpkv.WithBasicAuth(user, pass))
+	help, err := conf.ParseWithOptions(prefix, &cfg, conf.WithSource(src))
+
+# Source Precedence
+
+By default values are applied from parsers, then defaults, then sources, then
+dotenv files, then environment variables and finally command-line flags, with
+later values winning. The WithOrder option declares a different order, or
+leaves sources out. Sources are named by SourceDefault, SourceDotenv,
+SourceEnv, SourceFlag and the names of any parsers and sources registered,
+such as "yaml".
+
+	help, err := conf.ParseWithOptions(prefix, &cfg,
+		conf.WithParser(yaml.WithData(data)),
+		conf.WithOrder(conf.SourceDefault, conf.SourceEnv, "yaml"),
+	)
+
+# Field Provenance
+
+To find out where each value came from, pass a Result to WithResult. Once
+parsing succeeds it lists every field with its final value, the source that
+set it and any lower-priority sources that were overridden.
+
+	var res conf.Result
+	help, err := conf.ParseWithOptions(prefix, &cfg, conf.WithResult(&res))
+	...
+	p, _ := res.Lookup("Web.APIHost")
+	fmt.Println(p.Source) // "env"
+
+A Result can also be rendered for logging at startup as a table, JSON, YAML or
+dotenv lines. Masked values stay masked and noprint fields are left out.
+
+	out, err := res.Format(conf.FormatJSON)
+
+# Reloading
+
+Long-running programs can pick up changes without a restart by using a
+Reloader. Every reload runs the whole pipeline again into a fresh value
+returned by the constructor function, so Current never changes under a
+reader. A reload that would change a field tagged with immutable fails with
+an ImmutableError and the current config is kept.
+
+	r := conf.NewReloader(prefix, func() *Config { return &Config{} },
+		conf.WithParser(yaml.WithFile("config.yaml")),
+	)
+	if _, err := r.Load(); err != nil {
+		...
+	}
+
+	r.Subscribe(func(cfg *Config, changed []string) {
+		log.Println("config changed:", changed) // [Web.APIHost]
+	})
+
+	go r.Watch(ctx)
+
+Watch reloads on SIGHUP, or the signals given to WatchSignals, and when a
+file changes. Files read by yaml.WithFile are watched automatically and
+more can be added with WatchFiles. Reload can also be called directly.
+
 # Command Line Args
 
 Additionally, if the config struct has a field of the slice type conf.Args
@@ -144,6 +433,40 @@ such as this:
 	arg1 := cfg.Args.Num(1) // "http"
 	arg2 := cfg.Args.Num(2) // "" empty string: not enough arguments
 
+# Subcommands
+
+Programs made of several commands can give each command its own config
+struct with WithCommand. The struct passed to ParseWithOptions holds the
+global options, and the command's struct is parsed from the arguments after
+its name. The global options may also be given after the command, where they
+win over the ones given before it. Environment variables of a command are
+prefixed with the command name, such as ADMIN_MIGRATE_STEPS. A command that
+takes no options can pass an empty struct or nil.
+
+	var cfg struct {
+		Args  conf.Args
+		Debug bool
+	}
+	var migrate struct {
+		Steps int `conf:"default:1"`
+	}
+
+	conf.ParseWithOptions("ADMIN", &cfg,
+		conf.WithCommand("migrate", "run the database migrations", &migrate),
+		conf.WithCommand("seed", "load the seed data", &seed),
+	)
+
+	$ admin --debug migrate --steps=3
+
+	switch cfg.Args.Num(0) {
+	case "migrate":
+		...
+	}
+
+The --help output lists the commands and "admin migrate --help" shows the
+options of that command, followed by the global options. An argument that
+isn't a command is an error.
+
 # Version Information
 
 You can add a version with a description by adding the Version type to
@@ -0,0 +1,163 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SourceDotenv is the name of the dotenv source, for use with WithOrder.
+const SourceDotenv = "dotenv"
+
+// WithDotenv returns a ParseOption that reads environment variables from
+// dotenv files. The variables go through the same namespace and naming
+// rules as real environment variables, which take precedence over them.
+// When a variable is set in more than one file the last file wins. The
+// files are read on every parse and each one must exist.
+func WithDotenv(paths ...string) ParseOption {
+	return func(opts *parseOptions) {
+		opts.dotenv = append(opts.dotenv, paths...)
+	}
+}
+
+// newSourceDotenv reads the dotenv files into a source that is looked up
+// like the environment.
+func newSourceDotenv(namespace string, paths []string) (*env, error) {
+	var environ []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read dotenv: %w", err)
+		}
+
+		vars, err := parseDotenv(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("parse dotenv %s: %w", path, err)
+		}
+		environ = append(environ, vars...)
+	}
+
+	return newSourceEnvFrom(namespace, environ), nil
+}
+
+// parseDotenv parses the contents of a dotenv file into KEY=value pairs in
+// the order they appear. It supports:
+//
+//	# comments and blank lines
+//	export KEY=value
+//	KEY=value # with a trailing comment, after a space or tab
+//	KEY='literal value, may span lines'
+//	KEY="escapes \n \t \" \\ \$ are replaced, may span lines"
+func parseDotenv(data string) ([]string, error) {
+	var vars []string
+
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	var line int
+
+	for data != "" {
+		line++
+
+		text, rest, found := strings.Cut(data, "\n")
+		data = rest
+
+		text = strings.TrimLeft(text, " \t")
+		if strings.TrimSpace(text) == "" || text[0] == '#' {
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(text, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			text = strings.TrimLeft(rest, " \t")
+		}
+
+		key, value, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=value", line)
+		}
+		value = strings.TrimLeft(value, " \t")
+
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			value = cutComment(value)
+			vars = append(vars, key+"="+strings.TrimSpace(value))
+			continue
+		}
+
+		// A quoted value runs to the closing quote, which may be on a
+		// later line.
+		src := value[1:]
+		if found {
+			src += "\n" + data
+		}
+
+		v, n, ok := unquoteDotenv(src, value[0])
+		if !ok {
+			return nil, fmt.Errorf("line %d: unterminated quoted value", line)
+		}
+		start := line
+		line += strings.Count(src[:n], "\n")
+
+		// Whatever follows the closing quote on its line may only be a
+		// comment.
+		tail, next, more := strings.Cut(src[n:], "\n")
+		if tail = strings.TrimSpace(tail); tail != "" && tail[0] != '#' {
+			return nil, fmt.Errorf("line %d: unexpected text after quoted value", start)
+		}
+
+		data = ""
+		if more {
+			data = next
+		}
+
+		vars = append(vars, key+"="+v)
+	}
+
+	return vars, nil
+}
+
+// cutComment removes a trailing comment from an unquoted value. The # only
+// starts a comment when whitespace comes before it, so a value such as
+// color#1 is kept whole.
+func cutComment(value string) string {
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			return value[:i]
+		}
+	}
+	return value
+}
+
+// unquoteDotenv reads a quoted value up to the closing quote, returning the
+// value and the number of bytes consumed including the closing quote.
+// Escapes are only replaced in double quoted values.
+func unquoteDotenv(src string, quote byte) (string, int, bool) {
+	var sb strings.Builder
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == quote:
+			return sb.String(), i + 1, true
+
+		case c == '\\' && quote == '"' && i+1 < len(src):
+			i++
+			switch src[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '"', '\\', '$':
+				sb.WriteByte(src[i])
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(src[i])
+			}
+
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return "", 0, false
+}
@@ -0,0 +1,178 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A FieldError occurs when an error occurs updating an individual field
+// in the provided struct value.
+type FieldError struct {
+	fieldName string
+	key       string
+	typeName  string
+	value     string
+	source    string
+	envKey    string
+	flagKey   string
+	err       error
+}
+
+// newFieldError constructs a FieldError for a value the source provided
+// for the field. The value is masked when the field is tagged with mask
+// or noprint.
+func newFieldError(namespace string, field Field, source string, value string, err error) *FieldError {
+	if field.Options.Mask || field.Options.Noprint {
+		err = &maskedError{typeName: field.Field.Type().String()}
+		value = maskVal(value)
+	}
+
+	return &FieldError{
+		fieldName: field.Name,
+		key:       field.key,
+		typeName:  field.Field.Type().String(),
+		value:     value,
+		source:    source,
+		envKey:    envUsage(namespace, field),
+		flagKey:   strings.ToLower(strings.Join(field.FlagKey, `-`)),
+		err:       err,
+	}
+}
+
+func (err *FieldError) Error() string {
+	return fmt.Sprintf("conf: error assigning to field %s: converting '%s' to type %s. details: %s", err.fieldName, err.value, err.typeName, err.err)
+}
+
+// Unwrap returns the underlying conversion error. For fields tagged with
+// mask or noprint it is a generic error that doesn't hold the value.
+func (err *FieldError) Unwrap() error {
+	return err.err
+}
+
+// FieldName returns the name of the struct field.
+func (err *FieldError) FieldName() string {
+	return err.fieldName
+}
+
+// Key returns the path to the field, such as Web.APIHost.
+func (err *FieldError) Key() string {
+	return err.key
+}
+
+// TypeName returns the name of the type of the field.
+func (err *FieldError) TypeName() string {
+	return err.typeName
+}
+
+// Value returns the value that failed to convert. It is masked when the
+// field is tagged with mask or noprint.
+func (err *FieldError) Value() string {
+	return err.value
+}
+
+// Source returns the name of the source that provided the value.
+func (err *FieldError) Source() string {
+	return err.source
+}
+
+// EnvKey returns the environment variable that sets the field.
+func (err *FieldError) EnvKey() string {
+	return err.envKey
+}
+
+// FlagKey returns the long flag that sets the field, without dashes.
+func (err *FieldError) FlagKey() string {
+	return err.flagKey
+}
+
+// maskedError replaces the conversion error of a field tagged with mask or
+// noprint. Conversion errors hold the value in many forms, such as quoted
+// with escapes or split into elements, so none of their text is kept. The
+// original error is dropped as well, since errors.As would reach the value
+// through it, such as the Num field of a strconv.NumError.
+type maskedError struct {
+	typeName string
+}
+
+func (err *maskedError) Error() string {
+	return fmt.Sprintf("invalid value for type %s", err.typeName)
+}
+
+// A RequiredError occurs when a field tagged with required is not given a
+// value by any source.
+type RequiredError struct {
+	Field   string // The name of the struct field.
+	Key     string // The path to the field, such as Web.APIHost.
+	EnvKey  string // The environment variable that sets the field.
+	FlagKey string // The long flag that sets the field, without dashes.
+	envName string
+}
+
+func (err *RequiredError) Error() string {
+	return fmt.Sprintf("required field %s%s is missing value", err.Field, envSuffix(err.envName))
+}
+
+// A ZeroValueError occurs when a field tagged with notzero is left set to
+// its zero value.
+type ZeroValueError struct {
+	Field   string // The name of the struct field.
+	Key     string // The path to the field, such as Web.APIHost.
+	EnvKey  string // The environment variable that sets the field.
+	FlagKey string // The long flag that sets the field, without dashes.
+	Source  string // The source that set the zero value, empty if none did.
+	envName string
+}
+
+func (err *ZeroValueError) Error() string {
+	return fmt.Sprintf("field %s%s is set to zero value", err.Field, envSuffix(err.envName))
+}
+
+// envSuffix describes the env override of a field for an error message.
+func envSuffix(envName string) string {
+	if envName == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (env: %s)", envName)
+}
+
+// =============================================================================
+
+// collector gathers the errors found while parsing. Unless all errors
+// were requested, the first error reported stops the parse.
+type collector struct {
+	all    bool
+	errs   []error
+	failed map[string]bool
+}
+
+// newCollector constructs a collector for the parse options.
+func newCollector(all bool) *collector {
+	return &collector{
+		all:    all,
+		failed: make(map[string]bool),
+	}
+}
+
+// report records the error. It returns the error back when the parse
+// must stop and nil when the parse can continue.
+func (c *collector) report(err error) error {
+	if !c.all {
+		return err
+	}
+
+	c.errs = append(c.errs, err)
+	return nil
+}
+
+// reportField records an error that left the field without a usable
+// value, so no further errors are reported for it.
+func (c *collector) reportField(field Field, err error) error {
+	c.failed[field.key] = true
+	return c.report(err)
+}
+
+// err returns the collected errors as a single error, one per line.
+func (c *collector) err() error {
+	return errors.Join(c.errs...)
+}
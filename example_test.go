@@ -57,6 +57,8 @@ func Example() {
 	//
 	// ENVIRONMENT
 	//   SOME_OPTION  <string>    an example option
+	//
+	//   Add the _FILE suffix to any variable to read its value from a file.
 }
 
 // Demonstrates parsing configuration from command-line flags and environment variables.
@@ -4,27 +4,13 @@ import (
 	"encoding"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 	"unicode"
 )
 
-// A FieldError occurs when an error occurs updating an individual field
-// in the provided struct value.
-type FieldError struct {
-	fieldName string
-	typeName  string
-	value     string
-	err       error
-}
-
-func (err *FieldError) Error() string {
-	return fmt.Sprintf("conf: error assigning to field %s: converting '%s' to type %s. details: %s", err.fieldName, err.value, err.typeName, err.err)
-}
-
-// =============================================================================
-
 // Field maintains information about a field in the configuration struct.
 type Field struct {
 	Name    string
@@ -41,6 +27,16 @@ type Field struct {
 	// it must be written back to mapParent at mapKey via SetMapIndex.
 	mapParent reflect.Value
 	mapKey    reflect.Value
+
+	// The Go selector path to the field from the root struct, such as
+	// Web.APIHost. It uniquely identifies the field across extractions.
+	key string
+}
+
+// Key returns the Go selector path to the field from the root struct, such
+// as Web.APIHost, or Labels[env] for a map entry.
+func (f Field) Key() string {
+	return f.key
 }
 
 // FieldOptions maintain flag options for a given field.
@@ -55,10 +51,27 @@ type FieldOptions struct {
 	Mask          bool
 	NotZero       bool
 	Immutable     bool
+	Min           string
+	Max           string
+	MinLen        int
+	MaxLen        int
+	OneOf         []string
+	Pattern       string
+	File          string
+	Count         bool
+
+	// The compiled Pattern, so it is compiled once per field.
+	pattern *regexp.Regexp
 }
 
 // extractFields uses reflection to examine the struct and generate the keys.
 func extractFields(prefix []string, target any) ([]Field, error) {
+	return extractFieldsAt("", prefix, target)
+}
+
+// extractFieldsAt performs the work of extractFields for a struct located at
+// the specified key path.
+func extractFieldsAt(path string, prefix []string, target any) ([]Field, error) {
 	if prefix == nil {
 		prefix = []string{}
 	}
@@ -88,6 +101,10 @@ func extractFields(prefix []string, target any) ([]Field, error) {
 		}
 
 		fieldName := structField.Name
+		fieldPath := fieldName
+		if path != "" {
+			fieldPath = path + "." + fieldName
+		}
 
 		// Get and options.  TODO: Need more.
 		fieldOpts, err := parseTag(fieldTags)
@@ -95,6 +112,10 @@ func extractFields(prefix []string, target any) ([]Field, error) {
 			return nil, fmt.Errorf("conf: error parsing tags for field %s: %s", fieldName, err)
 		}
 
+		if err := checkRules(fieldOpts, f.Type()); err != nil {
+			return nil, fmt.Errorf("conf: error parsing tags for field %s: %s", fieldName, err)
+		}
+
 		// Generate the field key. This could be ignored.
 		fieldKey := append(prefix, camelSplit(fieldName)...)
 
@@ -127,7 +148,7 @@ func extractFields(prefix []string, target any) ([]Field, error) {
 			}
 
 			embeddedPtr := f.Addr().Interface()
-			innerFields, err := extractFields(innerPrefix, embeddedPtr)
+			innerFields, err := extractFieldsAt(fieldPath, innerPrefix, embeddedPtr)
 			if err != nil {
 				return nil, err
 			}
@@ -153,6 +174,7 @@ func extractFields(prefix []string, target any) ([]Field, error) {
 				Field:     f,
 				Options:   fieldOpts,
 				BoolField: f.Kind() == reflect.Bool,
+				key:       fieldPath,
 			}
 			fields = append(fields, fld)
 
@@ -186,6 +208,7 @@ func extractFields(prefix []string, target any) ([]Field, error) {
 						},
 						mapParent: f,
 						mapKey:    mapKey,
+						key:       fieldPath + "[" + keyStr + "]",
 					})
 				}
 			}
@@ -219,6 +242,8 @@ func parseTag(tagStr string) (FieldOptions, error) {
 				f.Mask = true
 			case "immutable":
 				f.Immutable = true
+			case "count":
+				f.Count = true
 			}
 		case 2:
 			tagPropVal := strings.TrimSpace(vals[1])
@@ -239,6 +264,31 @@ func parseTag(tagStr string) (FieldOptions, error) {
 				f.FlagName = tagPropVal
 			case "help":
 				f.Help = tagPropVal
+			case "file":
+				f.File = tagPropVal
+			case "min":
+				f.Min = tagPropVal
+			case "max":
+				f.Max = tagPropVal
+			case "minlen", "maxlen":
+				n, err := strconv.Atoi(tagPropVal)
+				if err != nil || n < 0 {
+					return f, fmt.Errorf("%s value must be a non-negative integer, got %q", tagProp, tagPropVal)
+				}
+				if tagProp == "minlen" {
+					f.MinLen = n
+				} else {
+					f.MaxLen = n
+				}
+			case "oneof":
+				f.OneOf = strings.Split(tagPropVal, "|")
+			case "pattern":
+				re, err := regexp.Compile(tagPropVal)
+				if err != nil {
+					return f, fmt.Errorf("pattern value is not a valid regular expression: %w", err)
+				}
+				f.Pattern = tagPropVal
+				f.pattern = re
 			}
 		default:
 			// TODO: Do we check for integrity issues here?
@@ -406,6 +456,86 @@ func processField(settingDefault bool, value string, field reflect.Value) error
 	return nil
 }
 
+// repeatable reports if the field collects every value of a repeated flag,
+// which is true of slices and maps that don't decode values themselves.
+func repeatable(field reflect.Value) bool {
+	typ := field.Type()
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Map {
+		return false
+	}
+
+	ptr := reflect.PointerTo(typ)
+	return !ptr.Implements(reflect.TypeFor[Setter]()) &&
+		!ptr.Implements(reflect.TypeFor[encoding.TextUnmarshaler]()) &&
+		!ptr.Implements(reflect.TypeFor[encoding.BinaryUnmarshaler]())
+}
+
+// processValues sets a slice or map field from every value of a repeated
+// flag. Each value is one element of a slice, so it may contain ';'. For a
+// map each value is either a single key=value pair or a list of key:value
+// pairs. A single value for a slice is split on ';' like with any source.
+func processValues(values []string, field reflect.Value) error {
+	typ := field.Type()
+
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+		if field.IsNil() {
+			field.Set(reflect.New(typ))
+		}
+		field = field.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Slice:
+		if len(values) == 1 {
+			return processField(false, values[0], field)
+		}
+
+		sl := reflect.MakeSlice(typ, len(values), len(values))
+		for i, val := range values {
+			if err := processField(false, val, sl.Index(i)); err != nil {
+				return err
+			}
+		}
+		field.Set(sl)
+
+	case reflect.Map:
+		mp := reflect.MakeMap(typ)
+		for _, val := range values {
+
+			// The value of a key=value pair is taken as is, so it may
+			// contain ':' and ';'.
+			if key, elem, ok := strings.Cut(val, "="); ok && !strings.Contains(key, ":") {
+				k := reflect.New(typ.Key()).Elem()
+				if err := processField(false, key, k); err != nil {
+					return err
+				}
+				v := reflect.New(typ.Elem()).Elem()
+				if err := processField(false, elem, v); err != nil {
+					return err
+				}
+				mp.SetMapIndex(k, v)
+				continue
+			}
+
+			pairs := reflect.New(typ).Elem()
+			if err := processField(false, val, pairs); err != nil {
+				return err
+			}
+			for iter := pairs.MapRange(); iter.Next(); {
+				mp.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+		field.Set(mp)
+	}
+
+	return nil
+}
+
 func interfaceFrom(field reflect.Value, fn func(any, *bool)) {
 
 	// It may be impossible for a struct field to fail this check.
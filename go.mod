@@ -3,6 +3,7 @@ module github.com/ardanlabs/conf/v3
 go 1.23.0
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/google/go-cmp v0.3.1
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,258 @@
+// Package httpkv provides a conf source for an HTTP key/value service with
+// a Consul style API.
+//
+// The keys under a prefix are fetched in one request:
+//
+//	GET <url>/v1/kv/<prefix>?recurse=true
+//
+// which responds with a JSON array of keys and base64 encoded values:
+//
+//	[{"Key": "app/web/api-host", "Value": "MC4wLjAuMDo0MDAw"}]
+//
+// A key is matched to a field by its path under the prefix, compared
+// without regard to case. Both the Go selector path, app/Web/APIHost, and
+// the flag key, app/web-api-host, name the Web.APIHost field. Map entries
+// are named like app/Labels[env].
+package httpkv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/conf/v3"
+)
+
+// Source fetches the keys under a prefix from an HTTP key/value service.
+// The keys are fetched once per parse and cached using the ETag of the
+// response, so an unchanged prefix isn't transferred again.
+type Source struct {
+	url        string
+	prefix     string
+	client     *http.Client
+	timeout    time.Duration
+	user       string
+	password   string
+	jsonValues bool
+
+	mu     sync.Mutex
+	etag   string
+	values map[string]string
+}
+
+// Option defines a functional option for configuring Source behavior.
+type Option func(*Source)
+
+// WithTimeout returns an Option that limits how long fetching the keys may
+// take. The default is 10 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(s *Source) {
+		s.timeout = d
+	}
+}
+
+// WithBasicAuth returns an Option that authenticates every request with
+// the username and password.
+func WithBasicAuth(user string, password string) Option {
+	return func(s *Source) {
+		s.user = user
+		s.password = password
+	}
+}
+
+// WithClient returns an Option that sends the requests with the client,
+// for custom transports or TLS settings.
+func WithClient(client *http.Client) Option {
+	return func(s *Source) {
+		s.client = client
+	}
+}
+
+// WithJSONValues returns an Option that decodes every value as JSON instead
+// of using the raw bytes. Strings are used as is, arrays become ; separated
+// lists and objects become ; separated key:value pairs, matching the
+// formats conf uses for slices and maps. A null value is treated as unset.
+func WithJSONValues() Option {
+	return func(s *Source) {
+		s.jsonValues = true
+	}
+}
+
+// New constructs a source for the keys under the prefix, served by the
+// key/value service at the url.
+func New(url string, prefix string, options ...Option) *Source {
+	s := Source{
+		url:     strings.TrimSuffix(url, "/"),
+		prefix:  strings.Trim(prefix, "/"),
+		client:  http.DefaultClient,
+		timeout: 10 * time.Second,
+	}
+	for _, option := range options {
+		option(&s)
+	}
+	return &s
+}
+
+// Name implements the conf.Namer interface.
+func (s *Source) Name() string {
+	return "httpkv"
+}
+
+// Load implements the conf.Loader interface. It fetches the keys under the
+// prefix unless they haven't changed since the last fetch.
+func (s *Source) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	segments := strings.Split(s.prefix, "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+
+	u := fmt.Sprintf("%s/v1/kv/%s?recurse=true", s.url, strings.Join(segments, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	if s.user != "" || s.password != "" {
+		req.SetBasicAuth(s.user, s.password)
+	}
+	if s.etag != "" && s.values != nil {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch keys: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil
+
+	case http.StatusNotFound:
+		// There are no keys under the prefix.
+		s.etag = resp.Header.Get("ETag")
+		s.values = make(map[string]string)
+		return nil
+
+	case http.StatusOK:
+
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("fetch keys: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var pairs []struct {
+		Key   string
+		Value []byte
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&pairs); err != nil {
+		return fmt.Errorf("decode keys: %w", err)
+	}
+
+	values := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		// The service matches the prefix as a string, so a prefix of app
+		// also returns keys such as apple/level.
+		name, ok := strings.CutPrefix(pair.Key, s.prefix)
+		if !ok || (s.prefix != "" && name != "" && name[0] != '/') {
+			continue
+		}
+
+		name = strings.TrimPrefix(name, "/")
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+
+		v := string(pair.Value)
+		if s.jsonValues {
+			var ok bool
+			if v, ok, err = decodeJSON(pair.Value); err != nil {
+				return fmt.Errorf("decode key %s: %w", pair.Key, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		values[strings.ToLower(name)] = v
+	}
+
+	s.etag = resp.Header.Get("ETag")
+	s.values = values
+	return nil
+}
+
+// Source implements the conf.Sourcer interface. It returns the value of
+// the key named after the field.
+func (s *Source) Source(fld conf.Field) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := []string{
+		strings.ReplaceAll(fld.Key(), ".", "/"),
+		strings.Join(fld.FlagKey, "-"),
+	}
+	for _, name := range names {
+		if v, exists := s.values[strings.ToLower(name)]; exists {
+			return v, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// decodeJSON converts a JSON value into the string format conf parses.
+func decodeJSON(data []byte) (string, bool, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return "", false, err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return "", false, nil
+
+	case string:
+		return v, true, nil
+
+	case []any:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = fmt.Sprint(e)
+		}
+		return strings.Join(parts, ";"), true, nil
+
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = k + ":" + fmt.Sprint(v[k])
+		}
+		return strings.Join(parts, ";"), true, nil
+	}
+
+	// Numbers keep the text they were written with.
+	return fmt.Sprint(v), true, nil
+}
@@ -0,0 +1,131 @@
+// Package httpkvtest provides an in-memory key/value service for testing
+// code that uses the httpkv package.
+package httpkvtest
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Server is an in-memory stand-in for a key/value service, so code using
+// httpkv.Source can be tested without the real service.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	values   map[string]string
+	user     string
+	password string
+	requests int
+}
+
+// NewServer starts a server holding the specified keys and values. It
+// must be closed when it is no longer needed.
+func NewServer(values map[string]string) *Server {
+	s := Server{
+		values: make(map[string]string),
+	}
+	for k, v := range values {
+		s.values[k] = v
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return &s
+}
+
+// RequireBasicAuth makes the server reject requests that don't provide the
+// username and password.
+func (s *Server) RequireBasicAuth(user string, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.user = user
+	s.password = password
+}
+
+// Set stores the value for the key.
+func (s *Server) Set(key string, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+}
+
+// Delete removes the key.
+func (s *Server) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, key)
+}
+
+// Requests returns the number of requests that returned keys, as opposed
+// to being answered with 304 Not Modified.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+// handle serves GET /v1/kv/<prefix>?recurse=true.
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.user != "" || s.password != "" {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != s.user || password != s.password {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+	}
+
+	prefix, ok := strings.CutPrefix(r.URL.Path, "/v1/kv/")
+	if r.Method != http.MethodGet || !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	type pair struct {
+		Key   string
+		Value []byte
+	}
+
+	var pairs []pair
+	for k, v := range s.values {
+		if strings.HasPrefix(k, prefix) {
+			pairs = append(pairs, pair{Key: k, Value: []byte(v)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+
+	data, err := json.Marshal(pairs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(data))
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if len(pairs) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.requests++
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,217 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// WithExpandEnv returns a ParseOption that expands references to
+// environment variables in default tags and in the strings set by parsers,
+// such as a yaml file:
+//
+//	${VAR}            the value of VAR, or empty if it isn't set
+//	${VAR:-fallback}  the value of VAR, or fallback if it is unset or empty
+//	$${               a literal ${
+//
+// The fallback may itself contain references. A $ that isn't followed by
+// a brace is left alone. Values from sources, environment variables and
+// flags are never expanded.
+func WithExpandEnv() ParseOption {
+	return func(opts *parseOptions) {
+		opts.expandEnv = true
+	}
+}
+
+// WithStrictExpandEnv returns a ParseOption that expands references like
+// WithExpandEnv, but fails the parse when a variable without a fallback
+// isn't set.
+func WithStrictExpandEnv() ParseOption {
+	return func(opts *parseOptions) {
+		opts.expandEnv = true
+		opts.expandStrict = true
+	}
+}
+
+// expandString expands the references in the string when expansion is
+// enabled.
+func (opts *parseOptions) expandString(s string) (string, error) {
+	if !opts.expandEnv {
+		return s, nil
+	}
+
+	e := expander{
+		lookup: os.LookupEnv,
+		strict: opts.expandStrict,
+	}
+	return e.expand(s)
+}
+
+// expandDefault expands the references in a default tag that refers to
+// other fields by their key path. References to anything other than a
+// field are expanded from the environment when expansion is enabled, and
+// left as they are otherwise.
+func (opts *parseOptions) expandDefault(s string, byKey map[string]Field) (string, error) {
+	e := expander{
+		lookup: func(name string) (string, bool) {
+			if field, exists := byKey[name]; exists {
+				return fieldString(field.Field), true
+			}
+			if opts.expandEnv {
+				return os.LookupEnv(name)
+			}
+			return "", false
+		},
+		strict:  opts.expandStrict,
+		partial: !opts.expandEnv,
+	}
+	return e.expand(s)
+}
+
+// expandField expands the references in the strings a field holds, which
+// covers string fields, pointers to them and string slices.
+func (opts *parseOptions) expandField(field Field) error {
+	if !opts.expandEnv {
+		return nil
+	}
+
+	v := field.Field
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.String:
+		s, err := opts.expandString(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		for i := 0; i < v.Len(); i++ {
+			s, err := opts.expandString(v.Index(i).String())
+			if err != nil {
+				return err
+			}
+			v.Index(i).SetString(s)
+		}
+
+	default:
+		return nil
+	}
+
+	if field.mapParent.IsValid() {
+		field.mapParent.SetMapIndex(field.mapKey, field.Field)
+	}
+
+	return nil
+}
+
+// expander replaces the ${NAME} and ${NAME:-fallback} references in a
+// string with the values returned by lookup.
+type expander struct {
+	lookup  func(name string) (string, bool)
+	strict  bool // fail on a reference lookup can't resolve
+	partial bool // leave a reference lookup can't resolve as it is
+}
+
+// expand returns s with its references replaced.
+func (e expander) expand(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			sb.WriteString("${")
+			i += 3
+
+		case strings.HasPrefix(s[i:], "${"):
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return "", errors.New("unterminated variable reference")
+			}
+
+			name, fallback, hasFallback := strings.Cut(s[i+2:end], ":-")
+			if name == "" {
+				return "", errors.New("empty variable reference")
+			}
+
+			v, exists := e.lookup(name)
+			switch {
+			case !exists && e.partial:
+				v = s[i : end+1]
+			case hasFallback && v == "":
+				var err error
+				if v, err = e.expand(fallback); err != nil {
+					return "", err
+				}
+			case !exists && e.strict:
+				return "", fmt.Errorf("variable %s is not set", name)
+			}
+
+			sb.WriteString(v)
+			i = end + 1
+
+		default:
+			sb.WriteByte(s[i])
+			i++
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// references returns the names of the references in s, including those
+// nested in fallbacks.
+func references(s string) []string {
+	var names []string
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			i += 3
+
+		case strings.HasPrefix(s[i:], "${"):
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return names
+			}
+
+			name, fallback, _ := strings.Cut(s[i+2:end], ":-")
+			names = append(names, name)
+			names = append(names, references(fallback)...)
+			i = end + 1
+
+		default:
+			i++
+		}
+	}
+	return names
+}
+
+// closingBrace returns the index of the brace that closes the reference
+// starting at start, allowing for references nested in a fallback.
+func closingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
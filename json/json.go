@@ -0,0 +1,248 @@
+// Package json provides json support for conf.
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// JSON provides support for unmarshalling JSON into the applications
+// config value. After the json is unmarshalled, the Parse function is
+// executed to apply defaults and overrides. Fields that are not set to
+// their zero after the json is parsed will have the defaults ignored.
+type JSON struct {
+	data    []byte
+	path    string
+	strict  bool
+	readErr error
+}
+
+// Option defines a functional option for configuring JSON behavior.
+type Option func(*JSON)
+
+// WithStrict returns an Option that fails the parse when the json contains
+// keys that don't match a field in the config struct.
+func WithStrict() Option {
+	return func(j *JSON) {
+		j.strict = true
+	}
+}
+
+// WithData accepts the json document as a slice of bytes.
+func WithData(data []byte, options ...Option) JSON {
+	j := JSON{
+		data: data,
+	}
+	for _, option := range options {
+		option(&j)
+	}
+	return j
+}
+
+// WithReader accepts a reader to read the json.
+func WithReader(r io.Reader, options ...Option) JSON {
+	var b bytes.Buffer
+	if _, err := b.ReadFrom(r); err != nil {
+		return JSON{readErr: err}
+	}
+
+	return WithData(b.Bytes(), options...)
+}
+
+// WithFile accepts the path to a json file. The file is read every time
+// the config is parsed, so a conf.Reloader picks up changes to it.
+func WithFile(path string, options ...Option) JSON {
+	j := JSON{
+		path: path,
+	}
+	for _, option := range options {
+		option(&j)
+	}
+	return j
+}
+
+// Name implements the conf.Namer interface.
+func (j JSON) Name() string {
+	return "json"
+}
+
+// Files implements the conf.FileBacked interface.
+func (j JSON) Files() []string {
+	if j.path == "" {
+		return nil
+	}
+	return []string{j.path}
+}
+
+// Process performs the actual processing of the json.
+func (j JSON) Process(prefix string, cfg any) error {
+	if j.readErr != nil {
+		return fmt.Errorf("read json: %w", j.readErr)
+	}
+
+	data := j.data
+	if j.path != "" {
+		var err error
+		if data, err = os.ReadFile(j.path); err != nil {
+			return fmt.Errorf("read json: %w", err)
+		}
+	}
+
+	// An empty document leaves the config untouched, the same as an empty
+	// yaml document.
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	if j.strict {
+		d.DisallowUnknownFields()
+	}
+
+	if err := d.Decode(cfg); err != nil {
+		return fmt.Errorf("unmarshal json: %w", locate(data, err))
+	}
+
+	if _, err := d.Token(); err != io.EOF {
+		return fmt.Errorf("unmarshal json: %w", locate(data, errors.New("unexpected data after top-level value")))
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// PositionError describes where in the document a json error occurred.
+type PositionError struct {
+	Offset int64 // The byte offset into the document.
+	Line   int   // The line number, starting at 1.
+	Column int   // The column in bytes, starting at 1.
+	Err    error
+}
+
+func (err *PositionError) Error() string {
+	return fmt.Sprintf("line %d, column %d (offset %d): %v", err.Line, err.Column, err.Offset, err.Err)
+}
+
+// Unwrap returns the underlying json error.
+func (err *PositionError) Unwrap() error {
+	return err.Err
+}
+
+// locate adds the position of the error in the document when it can be
+// determined.
+func locate(data []byte, err error) error {
+	offset := int64(-1)
+
+	var synErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &synErr):
+		// The offset is just past the byte that was rejected.
+		offset = max(synErr.Offset-1, 0)
+	case errors.As(err, &typeErr):
+		// The offset is just past the value, or past the opening delimiter
+		// of an object or array.
+		offset = valueOffset(data, typeErr.Offset)
+	case strings.HasPrefix(err.Error(), `json: unknown field "`):
+		name := strings.TrimSuffix(strings.TrimPrefix(err.Error(), `json: unknown field "`), `"`)
+		offset = keyOffset(data, name)
+	case err.Error() == "unexpected data after top-level value":
+		d := json.NewDecoder(bytes.NewReader(data))
+		var v any
+		if d.Decode(&v) == nil {
+			offset = d.InputOffset()
+		}
+	}
+
+	if offset < 0 || offset > int64(len(data)) {
+		return err
+	}
+
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+
+	return &PositionError{
+		Offset: offset,
+		Line:   line,
+		Column: column,
+		Err:    err,
+	}
+}
+
+// valueOffset returns the offset of the start of the token that ends at
+// the offset, or the offset itself if there is none.
+func valueOffset(data []byte, end int64) int64 {
+	d := json.NewDecoder(bytes.NewReader(data))
+
+	for {
+		start := d.InputOffset()
+		if _, err := d.Token(); err != nil {
+			return end
+		}
+
+		if d.InputOffset() == end {
+			// The offset before the token can include the separator and
+			// whitespace, so step forward to the token itself.
+			for start < end && bytes.IndexByte([]byte(" \t\r\n,:"), data[start]) >= 0 {
+				start++
+			}
+			return start
+		}
+	}
+}
+
+// keyOffset returns the offset of the first object key with the specified
+// name, or -1 if there is none.
+func keyOffset(data []byte, name string) int64 {
+	type frame struct {
+		object bool
+		key    bool
+	}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	var stack []frame
+
+	for {
+		start := d.InputOffset()
+		tok, err := d.Token()
+		if err != nil {
+			return -1
+		}
+
+		switch tok {
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		if n := len(stack); n > 0 && stack[n-1].object {
+			if stack[n-1].key {
+				stack[n-1].key = false
+				if s, ok := tok.(string); ok && s == name {
+					// The offset before the token can include the separator
+					// and whitespace, so step forward to the opening quote.
+					if i := bytes.IndexByte(data[start:], '"'); i >= 0 {
+						return start + int64(i)
+					}
+					return start
+				}
+				continue
+			}
+			stack[n-1].key = true
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, frame{object: true, key: true})
+		case json.Delim('['):
+			stack = append(stack, frame{})
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package conf
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// FileBacked is implemented by parsers and sources that read their values
+// from files. Reloader.Watch polls these files for changes.
+type FileBacked interface {
+	Files() []string
+}
+
+// An ImmutableError occurs when a reload would change the value of a field
+// tagged with immutable.
+type ImmutableError struct {
+	Field string // The name of the struct field.
+	Key   string // The path to the field, such as Web.APIHost.
+}
+
+func (err *ImmutableError) Error() string {
+	return fmt.Sprintf("immutable field %s can't be changed by a reload", err.Key)
+}
+
+// =============================================================================
+
+// Reloader re-runs the parse pipeline while the program is running so
+// configuration changes are picked up without a restart. Every load parses
+// into a fresh config value, which replaces the current one only when it
+// parses cleanly and leaves the immutable fields unchanged.
+type Reloader[T any] struct {
+	prefix  string
+	newCfg  func() *T
+	options []ParseOption
+
+	current atomic.Pointer[T]
+
+	mu   sync.Mutex
+	subs []func(cfg *T, changed []string)
+}
+
+// NewReloader constructs a Reloader. The newCfg function returns the value
+// each load starts from, which is where Version information is set.
+func NewReloader[T any](prefix string, newCfg func() *T, options ...ParseOption) *Reloader[T] {
+	return &Reloader[T]{
+		prefix:  prefix,
+		newCfg:  newCfg,
+		options: options,
+	}
+}
+
+// Load performs the initial parse. It behaves like ParseWithOptions,
+// returning the usage or version information along with ErrHelpWanted or
+// ErrVersionWanted.
+func (r *Reloader[T]) Load() (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg := r.newCfg()
+	info, err := ParseWithOptions(r.prefix, cfg, r.options...)
+	if err != nil {
+		return info, err
+	}
+
+	r.current.Store(cfg)
+	return "", nil
+}
+
+// Current returns the most recently loaded config. The value must not be
+// modified, since it is shared with every caller.
+func (r *Reloader[T]) Current() *T {
+	return r.current.Load()
+}
+
+// Subscribe registers a function to call after every reload that changes
+// the config. It receives the new config and the key paths of the fields
+// that changed, such as Web.APIHost.
+func (r *Reloader[T]) Subscribe(fn func(cfg *T, changed []string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subs = append(r.subs, fn)
+}
+
+// Reload parses the config again from every source. The current config is
+// kept when parsing fails or when an immutable field would change. The
+// subscribers are called once the lock is released, so they can call
+// Reload or Subscribe themselves.
+func (r *Reloader[T]) Reload() error {
+	cfg, changed, subs, err := r.swap()
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range subs {
+		fn(cfg, changed)
+	}
+
+	return nil
+}
+
+// swap parses a fresh config and makes it the current one. It returns the
+// key paths of the fields that changed along with a copy of the
+// subscribers to notify, which is empty when nothing changed.
+func (r *Reloader[T]) swap() (*T, []string, []func(cfg *T, changed []string), error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg := r.newCfg()
+	if _, err := ParseWithOptions(r.prefix, cfg, r.options...); err != nil {
+		return nil, nil, nil, err
+	}
+
+	old := r.current.Load()
+	if old == nil {
+		r.current.Store(cfg)
+		return cfg, nil, nil, nil
+	}
+
+	changed, err := diffFields(old, cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if len(changed) == 0 {
+		return cfg, nil, nil, nil
+	}
+
+	r.current.Store(cfg)
+	return cfg, changed, slices.Clone(r.subs), nil
+}
+
+// diffFields returns the key paths of the fields that differ between the
+// two configs, failing if any of them is immutable.
+func diffFields(old any, cfg any) ([]string, error) {
+	oldFields, err := extractFields(nil, old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := extractFields(nil, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	before := snapshot(oldFields)
+
+	var changed []string
+	for _, field := range newFields {
+		if value, exists := before[field.key]; exists && value == fieldString(field.Field) {
+			continue
+		}
+
+		if field.Options.Immutable {
+			return nil, &ImmutableError{Field: field.Name, Key: field.key}
+		}
+		changed = append(changed, field.key)
+	}
+
+	return changed, nil
+}
+
+// =============================================================================
+
+// watchOptions configures the behavior of Watch.
+type watchOptions struct {
+	signals  []os.Signal
+	files    []string
+	interval time.Duration
+	onError  func(error)
+}
+
+// WatchOption defines a functional option for configuring Watch behavior.
+type WatchOption func(*watchOptions)
+
+// WatchSignals returns a WatchOption that reloads the config when one of
+// the signals is received. Without this option Watch reloads on SIGHUP.
+func WatchSignals(sigs ...os.Signal) WatchOption {
+	return func(opts *watchOptions) {
+		opts.signals = append(opts.signals, sigs...)
+	}
+}
+
+// WatchFiles returns a WatchOption that reloads the config when one of the
+// files changes. The config file, dotenv files and the files of parsers and
+// sources that implement FileBacked are watched without this option.
+func WatchFiles(paths ...string) WatchOption {
+	return func(opts *watchOptions) {
+		opts.files = append(opts.files, paths...)
+	}
+}
+
+// WatchInterval returns a WatchOption that sets how often files are
+// checked for changes. The default is one second.
+func WatchInterval(d time.Duration) WatchOption {
+	return func(opts *watchOptions) {
+		opts.interval = d
+	}
+}
+
+// WatchErrors returns a WatchOption that receives the errors of reloads
+// triggered by Watch, such as a file that no longer parses.
+func WatchErrors(fn func(error)) WatchOption {
+	return func(opts *watchOptions) {
+		opts.onError = fn
+	}
+}
+
+// Watch reloads the config whenever a watched signal is received or a
+// watched file changes, until the context is canceled.
+func (r *Reloader[T]) Watch(ctx context.Context, options ...WatchOption) error {
+	opts := watchOptions{
+		interval: time.Second,
+		onError:  func(error) {},
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	if len(opts.signals) == 0 {
+		opts.signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	var popts parseOptions
+	for _, option := range r.options {
+		option(&popts)
+	}
+	opts.files = append(opts.files, popts.dotenv...)
+	if popts.configFile && len(os.Args) > 0 {
+		if flag, err := newSourceFlag(os.Args[1:], popts.flagSpec(r.newCfg())); err == nil {
+			if path, exists, _ := configPath(r.prefix, flag); exists {
+				opts.files = append(opts.files, path)
+			}
+		}
+	}
+	for _, stg := range popts.stages {
+		for _, v := range []any{stg.parser, stg.source} {
+			if fb, ok := v.(FileBacked); ok {
+				opts.files = append(opts.files, fb.Files()...)
+			}
+		}
+	}
+	slices.Sort(opts.files)
+	opts.files = slices.Compact(opts.files)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, opts.signals...)
+	defer signal.Stop(sigs)
+
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+
+	prints := fingerprints(opts.files)
+
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return nil
+			}
+			return ctx.Err()
+
+		case <-sigs:
+			if err := r.Reload(); err != nil {
+				opts.onError(err)
+			}
+
+		case <-ticker.C:
+			current := fingerprints(opts.files)
+			if slices.Equal(prints, current) {
+				continue
+			}
+			prints = current
+
+			if err := r.Reload(); err != nil {
+				opts.onError(err)
+			}
+		}
+	}
+}
+
+// fingerprints describes the state of each file so changes can be seen.
+// The contents are hashed, since a rewrite of the same size may keep the
+// modification time within the resolution of the filesystem. Symlinks are
+// also described by their target, so a swapped link is noticed.
+func fingerprints(paths []string) []string {
+	prints := make([]string, len(paths))
+	for i, path := range paths {
+		var fp string
+		if target, err := os.Readlink(path); err == nil {
+			fp = target + ">"
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			fp += fmt.Sprintf("%x", sha256.Sum256(data))
+		}
+		prints[i] = fp
+	}
+	return prints
+}
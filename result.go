@@ -0,0 +1,276 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+// Result reports where the final value of every field came from. Pass a
+// Result to WithResult to have it filled in by ParseWithOptions.
+type Result struct {
+	Fields []Provenance
+}
+
+// Provenance describes the final value of a field and the sources that
+// provided it. Fields tagged with noprint are not reported and the values
+// of fields tagged with mask are masked.
+type Provenance struct {
+	Name     string   `json:"name" yaml:"name"`                             // The name of the struct field.
+	Key      string   `json:"key" yaml:"key"`                               // The path to the field, such as Web.APIHost.
+	Env      string   `json:"env" yaml:"env"`                               // The environment variable for the field.
+	Flag     string   `json:"flag" yaml:"flag"`                             // The long flag for the field, without dashes.
+	Value    string   `json:"value" yaml:"value"`                           // The final value of the field.
+	Source   string   `json:"source,omitempty" yaml:"source,omitempty"`     // The source that set the value, empty if none did.
+	Shadowed []string `json:"shadowed,omitempty" yaml:"shadowed,omitempty"` // Lower-priority sources whose values were overridden.
+}
+
+// Lookup returns the provenance for the field with the specified key.
+func (r *Result) Lookup(key string) (Provenance, bool) {
+	for _, p := range r.Fields {
+		if p.Key == key {
+			return p, true
+		}
+	}
+	return Provenance{}, false
+}
+
+// Format identifies an output format for Result.Format.
+type Format int
+
+// Set of formats a Result can be rendered in.
+const (
+	FormatTable  Format = iota // Aligned columns with the flag, env, value and source.
+	FormatJSON                 // A JSON array of Provenance values.
+	FormatYAML                 // A YAML sequence of Provenance values.
+	FormatDotenv               // KEY=value lines using the environment variable names.
+)
+
+// Format renders the result in the specified format. Masked values stay
+// masked and noprint fields are left out, just like with String.
+func (r *Result) Format(format Format) (string, error) {
+	var sb strings.Builder
+
+	switch format {
+	case FormatTable:
+		w := new(tabwriter.Writer)
+		w.Init(&sb, 0, 4, 2, ' ', 0)
+
+		fmt.Fprintln(w, "FLAG\tENV\tVALUE\tSOURCE")
+		for _, p := range r.Fields {
+			fmt.Fprintf(w, "--%s\t%s\t%s\t%s\n", p.Flag, p.Env, p.Value, p.Source)
+		}
+		w.Flush()
+
+	case FormatJSON:
+		fields := r.Fields
+		if fields == nil {
+			fields = []Provenance{}
+		}
+		data, err := json.MarshalIndent(fields, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("marshal json: %w", err)
+		}
+		sb.Write(data)
+		sb.WriteString("\n")
+
+	case FormatYAML:
+		if len(r.Fields) == 0 {
+			return "[]\n", nil
+		}
+		for _, p := range r.Fields {
+			fmt.Fprintf(&sb, "- name: %s\n", yamlQuote(p.Name))
+			fmt.Fprintf(&sb, "  key: %s\n", yamlQuote(p.Key))
+			fmt.Fprintf(&sb, "  env: %s\n", yamlQuote(p.Env))
+			fmt.Fprintf(&sb, "  flag: %s\n", yamlQuote(p.Flag))
+			fmt.Fprintf(&sb, "  value: %s\n", yamlQuote(p.Value))
+			if p.Source != "" {
+				fmt.Fprintf(&sb, "  source: %s\n", yamlQuote(p.Source))
+			}
+			if len(p.Shadowed) > 0 {
+				sb.WriteString("  shadowed:\n")
+				for _, stage := range p.Shadowed {
+					fmt.Fprintf(&sb, "    - %s\n", yamlQuote(stage))
+				}
+			}
+		}
+
+	case FormatDotenv:
+		for _, p := range r.Fields {
+			fmt.Fprintf(&sb, "%s=%s\n", p.Env, dotenvQuote(p.Value))
+		}
+
+	default:
+		return "", fmt.Errorf("unknown format %d", format)
+	}
+
+	return sb.String(), nil
+}
+
+// dotenvQuote quotes a value for a dotenv file when it contains characters
+// that would otherwise be interpreted.
+func dotenvQuote(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\n\r\"'\\$#=`") {
+		return v
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`", "\n", `\n`, "\r", `\r`)
+	return `"` + r.Replace(v) + `"`
+}
+
+// yamlQuote returns the value as a YAML scalar. The value is double quoted
+// when a YAML reader would otherwise see something other than the same
+// string, such as a number, a bool, a comment or a mapping.
+func yamlQuote(v string) string {
+	switch {
+	case v == "",
+		strings.TrimSpace(v) != v,
+		strings.ContainsAny(v[:1], "-?:,[]{}#&*!|>'\"%@`"),
+		strings.HasSuffix(v, ":"),
+		strings.Contains(v, ": "),
+		strings.Contains(v, " #"),
+		strings.IndexFunc(v, func(r rune) bool { return !unicode.IsPrint(r) }) >= 0:
+		return strconv.Quote(v)
+	}
+
+	switch strings.ToLower(v) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		return strconv.Quote(v)
+	}
+
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return strconv.Quote(v)
+	}
+	if _, err := strconv.ParseInt(v, 0, 64); err == nil {
+		return strconv.Quote(v)
+	}
+
+	return v
+}
+
+// WithResult returns a ParseOption that records the provenance of every
+// field into the provided Result once parsing completes successfully.
+func WithResult(res *Result) ParseOption {
+	return func(opts *parseOptions) {
+		opts.result = res
+	}
+}
+
+// =============================================================================
+
+// origin records a stage that provided a value for a field.
+type origin struct {
+	stage   string
+	applied bool
+	source  bool
+}
+
+// origins tracks the stages that provided a value for each field, keyed
+// by field key.
+type origins map[string][]origin
+
+// add records a stage against the specified field.
+func (o origins) add(field Field, stage string, applied bool, source bool) {
+	o[field.key] = append(o[field.key], origin{stage: stage, applied: applied, source: source})
+}
+
+// provided reports if a source, as opposed to a default or parser, set
+// the value of the field.
+func (o origins) provided(field Field) bool {
+	for _, org := range o[field.key] {
+		if org.applied && org.source {
+			return true
+		}
+	}
+	return false
+}
+
+// source returns the name of the stage that set the final value of the
+// field, or an empty string if none did.
+func (o origins) source(field Field) string {
+	var name string
+	for _, org := range o[field.key] {
+		if org.applied {
+			name = org.stage
+		}
+	}
+	return name
+}
+
+// snapshot captures the current value of every field so changes made by
+// a parser can be detected.
+func snapshot(fields []Field) map[string]string {
+	values := make(map[string]string, len(fields))
+	for _, field := range fields {
+		values[field.key] = fieldString(field.Field)
+	}
+	return values
+}
+
+// record builds the provenance of each field into the result.
+func (o origins) record(res *Result, namespace string, fields []Field) {
+	res.Fields = res.Fields[:0]
+
+	for _, field := range fields {
+		if skipField(field) || field.Options.Noprint {
+			continue
+		}
+
+		p := Provenance{
+			Name:  field.Name,
+			Key:   field.key,
+			Env:   envUsage(namespace, field),
+			Flag:  strings.ToLower(strings.Join(field.FlagKey, `-`)),
+			Value: fieldString(field.Field),
+		}
+		if field.Options.Mask {
+			p.Value = maskVal(p.Value)
+		}
+
+		orgs := o[field.key]
+		winner := -1
+		for i, org := range orgs {
+			if org.applied {
+				winner = i
+			}
+		}
+
+		for i, org := range orgs {
+			if i == winner {
+				p.Source = org.stage
+				continue
+			}
+			p.Shadowed = append(p.Shadowed, org.stage)
+		}
+
+		res.Fields = append(res.Fields, p)
+	}
+}
+
+// fieldString returns the stringified value of a field, following pointers.
+func fieldString(field reflect.Value) string {
+	for field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return ""
+		}
+		field = field.Elem()
+	}
+
+	if !field.CanInterface() {
+		return ""
+	}
+	return fmt.Sprintf("%v", field.Interface())
+}
+
+// isZeroValue reports if the field is zero, following a non-nil pointer
+// the same way processField does when setting a default.
+func isZeroValue(field reflect.Value) bool {
+	if field.Kind() == reflect.Pointer && !field.IsNil() {
+		return field.Elem().IsZero()
+	}
+	return field.IsZero()
+}
@@ -3,7 +3,11 @@ package conf
 import (
 	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -15,13 +19,37 @@ var (
 	ErrVersionWanted = errors.New("version wanted")
 )
 
-// sourcer provides the ability to source data from a configuration source.
+// Names of the built-in sources, for use with WithOrder.
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// stage is a single step of the parse pipeline. A stage holds either a
+// parser or a source, except for the defaults stage which holds neither.
+type stage struct {
+	name   string
+	base   string
+	parser Parsers
+	source Sourcer
+}
+
+// Sourcer provides the ability to source data from a configuration source.
 // Consider the use of lazy-loading for sourcing large datasets or systems.
-type sourcer interface {
+type Sourcer interface {
 
 	// Source takes the field key and attempts to locate that key in its
-	// configuration data. Returns true if found with the value.
-	Source(fld Field) (string, bool)
+	// configuration data. Returns true if found with the value. An error
+	// stops the parse and is reported against the field.
+	Source(fld Field) (string, bool, error)
+}
+
+// Loader is implemented by sources that fetch their data in bulk. Load is
+// called once at the start of every parse, before any field is sourced, so
+// the data is current when the config is reloaded.
+type Loader interface {
+	Load() error
 }
 
 // =============================================================================
@@ -29,12 +57,21 @@ type sourcer interface {
 
 // env is a source for environmental variables.
 type env struct {
-	m map[string]string
+	m        map[string]string
+	uspace   string
+	tagFiles bool            // read the file named by a field's file tag
+	known    map[string]bool // variables of fields, never read as _FILE
 }
 
 // newSourceEnv accepts a namespace and parses the environment into a Env for
 // use by the configuration package.
 func newSourceEnv(namespace string) *env {
+	return newSourceEnvFrom(namespace, os.Environ())
+}
+
+// newSourceEnvFrom accepts a namespace and a list of KEY=value pairs in the
+// format of os.Environ and parses them into a Env.
+func newSourceEnvFrom(namespace string, environ []string) *env {
 	m := make(map[string]string)
 
 	// Create the uppercase version to meet the standard {NAMESPACE_} format.
@@ -45,7 +82,7 @@ func newSourceEnv(namespace string) *env {
 	}
 
 	// Loop and match each variable using the uppercase namespace.
-	for _, val := range os.Environ() {
+	for _, val := range environ {
 		if !strings.HasPrefix(val, uspace) {
 			continue
 		}
@@ -54,15 +91,61 @@ func newSourceEnv(namespace string) *env {
 		m[strings.ToUpper(strings.TrimPrefix(val[0:idx], uspace))] = val[idx+1:]
 	}
 
-	return &env{m: m}
+	return &env{m: m, uspace: uspace}
 }
 
-// Source implements the conf.sourcer interface. It returns the stringified value
-// stored at the specified key from the environment.
-func (e *env) Source(fld Field) (string, bool) {
+// Source implements the conf.Sourcer interface. It returns the stringified value
+// stored at the specified key from the environment. When the key isn't set,
+// the value is read from the file named by the key with a _FILE suffix, or
+// by the field's file tag.
+func (e *env) Source(fld Field) (string, bool, error) {
 	k := strings.ToUpper(strings.ReplaceAll(strings.Join(fld.EnvKey, `_`), `-`, `_`))
 	v, ok := e.m[k]
-	return v, ok
+
+	// A variable that belongs to another field is never a file reference.
+	path, isFile := e.m[k+fileSuffix]
+	if e.known[k+fileSuffix] {
+		isFile = false
+	}
+
+	switch {
+	case ok && isFile:
+		return "", false, fmt.Errorf("both %s%s and %s%s%s are set", e.uspace, k, e.uspace, k, fileSuffix)
+	case ok:
+		return v, true, nil
+	case isFile:
+		v, err := readSecretFile(path)
+		if err != nil {
+			return "", false, err
+		}
+		return v, true, nil
+	case e.tagFiles && fld.Options.File != "":
+		v, err := readSecretFile(fld.Options.File)
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return v, true, nil
+	}
+
+	return "", false, nil
+}
+
+// fileSuffix is added to a variable name to provide the path to a file
+// holding the value instead of the value itself.
+const fileSuffix = "_FILE"
+
+// readSecretFile returns the contents of the file, minus a trailing newline.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+
+	v := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(v, "\r"), nil
 }
 
 // envUsage constructs a usage string for the environment variable.
@@ -80,20 +163,184 @@ func envUsage(namespace string, fld Field) string {
 type flagValue struct {
 	HasValue bool
 	Value    string
+	pos      int // position of the flag on the command line
 }
 
-// flag is a source for command line arguments.
+// flag is a source for command line arguments. A flag may be given more
+// than once, so every value is kept in the order given.
 type flag struct {
-	m        map[string]flagValue
+	m        map[string][]flagValue
 	consumed map[string]bool // tracks which flags have been consumed
 	args     []string
+	spec     flagSpec
+}
+
+// flagSpec describes the flags a config struct accepts. It is needed to
+// tell combined short flags from a long flag given with a single dash, and
+// to stop the flags in front of the name of a command.
+type flagSpec struct {
+	long        map[string]bool // reports if the long flag takes no value
+	short       map[string]bool // reports if the short flag takes no value
+	counts      map[string]bool // flags of fields tagged with count
+	commands    []string
+	strictBools bool
+	help        builtinFlag
+	version     builtinFlag
+}
+
+// flagSpec returns the flags accepted by the config struct along with the
+// flags reserved by the options. A struct that can't be parsed is reported
+// by parse, so the spec just leaves its fields out.
+func (opts *parseOptions) flagSpec(cfg any) flagSpec {
+	spec := flagSpec{
+		long:        make(map[string]bool),
+		short:       make(map[string]bool),
+		counts:      make(map[string]bool),
+		commands:    opts.commandNames(),
+		strictBools: opts.strictBools,
+		help:        opts.helpFlag(),
+		version:     opts.versionFlag(),
+	}
+
+	fields, _ := extractFields(nil, cfg)
+	if opts.configFile {
+		fields = append(fields, configField())
+	}
+
+	for _, fld := range fields {
+		noValue := fld.BoolField || fld.Options.Count
+
+		long := strings.ToLower(strings.Join(fld.FlagKey, `-`))
+		spec.long[long] = noValue
+		spec.counts[long] = fld.Options.Count
+
+		if fld.Options.ShortFlagChar != 0 {
+			short := strings.ToLower(string(fld.Options.ShortFlagChar))
+			spec.short[short] = noValue
+			spec.counts[short] = fld.Options.Count
+		}
+	}
+
+	// The help and version flags are checked first, so they win over any
+	// field that uses the same name.
+	for _, bf := range []builtinFlag{spec.help, spec.version} {
+		if bf.name != "" {
+			spec.long[bf.name] = true
+		}
+		if bf.short != 0 {
+			spec.short[strings.ToLower(string(bf.short))] = true
+		}
+	}
+	if spec.help.enabled() {
+		spec.short["?"] = true
+	}
+
+	return spec
+}
+
+// merge returns a copy of the spec that also accepts the flags of the
+// other spec. The flags of the spec win over the ones of the other spec
+// with the same name.
+func (spec flagSpec) merge(other flagSpec) flagSpec {
+	spec.long = maps.Clone(spec.long)
+	spec.short = maps.Clone(spec.short)
+	spec.counts = maps.Clone(spec.counts)
+
+	for name, noValue := range other.long {
+		if _, exists := spec.long[name]; !exists {
+			spec.long[name] = noValue
+			spec.counts[name] = other.counts[name]
+		}
+	}
+	for name, noValue := range other.short {
+		if _, exists := spec.short[name]; !exists {
+			spec.short[name] = noValue
+			spec.counts[name] = other.counts[name]
+		}
+	}
+
+	return spec
+}
+
+// combined reports if the name given after a single dash holds combined
+// short flags. That is the case when the name is longer than one character,
+// isn't the name of a long flag and starts with a short flag.
+func (spec flagSpec) combined(name string) bool {
+	if len(name) < 2 {
+		return false
+	}
+	if _, isLong := spec.lookup(name); isLong {
+		return false
+	}
+
+	_, exists := spec.short[strings.ToLower(name[:1])]
+	return exists
+}
+
+// lookup reports if the flag takes no value, like a bool, and if the flag
+// exists, counting the negated form of the bool flags.
+func (spec flagSpec) lookup(name string) (noValue bool, exists bool) {
+	name = strings.ToLower(name)
+
+	if len(name) == 1 {
+		noValue, exists = spec.short[name]
+		return noValue, exists
+	}
+
+	if noValue, exists = spec.long[name]; exists {
+		return noValue, exists
+	}
+
+	if name, negated := strings.CutPrefix(name, negatePrefix); negated {
+		isBool := spec.long[name] && !spec.counts[name]
+		return isBool, isBool
+	}
+
+	return false, false
+}
+
+// isHelp reports if the flag asks for help.
+func (spec flagSpec) isHelp(name string) bool {
+	return spec.help.is(name) || (name == "?" && spec.help.enabled())
+}
+
+// isVersion reports if the flag asks for the version.
+func (spec flagSpec) isVersion(name string) bool {
+	return spec.version.is(name)
 }
 
 // newSourceFlag parsing a string of command line arguments. NewFlag will return
-// errHelpWanted, if the help flag is identified. This code is adapted
-// from the Go standard library flag package.
-func newSourceFlag(args []string) (*flag, error) {
-	m := make(map[string]flagValue)
+// errHelpWanted, if the help flag is identified. The name of a command is
+// never taken as the value of a flag, so the flags stop in front of it. This
+// code is adapted from the Go standard library flag package.
+//
+// Short flags can be combined after a single dash as described by the spec.
+// They are read as bools up to the first flag that takes a value, which
+// takes the rest of the argument as its value, or the next argument when
+// nothing is left.
+func newSourceFlag(args []string, spec flagSpec) (*flag, error) {
+	m := make(map[string][]flagValue)
+	pos := 0
+
+	// Store the flag/value pair.
+	store := func(name string, hasValue bool, value string) {
+		m[name] = append(m[name], flagValue{
+			HasValue: hasValue,
+			Value:    value,
+			pos:      pos,
+		})
+		pos++
+	}
+
+	// takeValue returns the next argument when it can be the value of a flag.
+	takeValue := func() (string, bool) {
+		if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' && !slices.Contains(spec.commands, args[0]) {
+			value := args[0]
+			args = args[1:]
+			return value, true
+		}
+		return "", false
+	}
 
 	if len(args) != 0 {
 		for len(args) != 0 {
@@ -122,6 +369,45 @@ func newSourceFlag(args []string) (*flag, error) {
 
 			// It's a flag. Does it have an argument?
 			args = args[1:]
+
+			// Combined short flags, such as -xvf or -p8080.
+			if before, _, _ := strings.Cut(name, "="); numMinuses == 1 && spec.combined(before) {
+				for i, c := range name {
+					short := string(c)
+
+					switch {
+					case spec.isHelp(short):
+						return nil, ErrHelpWanted
+					case spec.isVersion(short):
+						return nil, ErrVersionWanted
+					}
+
+					// Unknown flags are kept like bools so strict flags can
+					// report them. Counters are kept without a value, so
+					// each one counts once.
+					noValue, exists := spec.short[strings.ToLower(short)]
+					switch {
+					case spec.counts[strings.ToLower(short)]:
+						store(short, false, "")
+						continue
+					case noValue || !exists:
+						store(short, true, "true")
+						continue
+					}
+
+					// The rest of the argument is the value, optionally
+					// after an equals sign.
+					value := strings.TrimPrefix(name[i+len(short):], "=")
+					hasValue := value != ""
+					if !hasValue {
+						value, hasValue = takeValue()
+					}
+					store(short, hasValue, value)
+					break
+				}
+				continue
+			}
+
 			hasValue := false
 			value := ""
 			for i := 1; i < len(name); i++ { // equals cannot be first
@@ -133,21 +419,35 @@ func newSourceFlag(args []string) (*flag, error) {
 				}
 			}
 
-			if name == "help" || name == "h" || name == "?" {
+			if spec.isHelp(name) {
 				return nil, ErrHelpWanted
 			}
 
-			if name == "version" || name == "v" {
+			if spec.isVersion(name) {
 				return nil, ErrVersionWanted
 			}
 
 			// If we don't have a value yet, it's possible the flag was not in the
 			// -flag=value format which means it might still have a value which would be
 			// the next argument, provided the next argument isn't a flag.
-			if !hasValue {
-				if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
-					// Doesn't look like a flag. Must be a value.
-					value, args = args[0], args[1:]
+			//
+			// With strict bools, a bool flag only takes a value after an
+			// equals sign, and a counter never takes the next argument.
+			//
+			// A known bool only takes a following true or false, so any other
+			// argument stays a positional argument even when the flag source
+			// is left out of the order.
+			noValue, _ := spec.lookup(name)
+			switch {
+			case hasValue || (noValue && (spec.strictBools || spec.counts[strings.ToLower(name)])):
+				// The value was given with an equals sign, or none is taken.
+			case noValue:
+				if len(args) > 0 && (args[0] == "true" || args[0] == "false") {
+					value, hasValue, args = args[0], true, args[1:]
+				}
+			default:
+				var found bool
+				if value, found = takeValue(); found {
 
 					// Found a bug with the single bool where a value may or may
 					// not be provided.
@@ -157,27 +457,27 @@ func newSourceFlag(args []string) (*flag, error) {
 				}
 			}
 
-			// Store the flag/value pair.
-			m[name] = flagValue{
-				HasValue: hasValue,
-				Value:    value,
-			}
+			store(name, hasValue, value)
 		}
 	}
 
-	return &flag{m: m, consumed: make(map[string]bool), args: args}, nil
+	return &flag{m: m, consumed: make(map[string]bool), args: args, spec: spec}, nil
 }
 
 // source returns the stringified value stored at the specified key with special handling for bool flags.
 func (f *flag) source(key string, isBool bool) (string, bool) {
 	k := strings.ToLower(key)
 
-	val, found := f.m[k]
-	if !found || !isBool {
-		if found {
-			// Mark this flag as consumed
-			f.consumed[k] = true
-		}
+	vals, found := f.m[k]
+	if !found {
+		return "", false
+	}
+
+	// The last value wins when the flag is given more than once.
+	val := vals[len(vals)-1]
+	if !isBool {
+		// Mark this flag as consumed
+		f.consumed[k] = true
 		return val.Value, found
 	}
 
@@ -187,7 +487,9 @@ func (f *flag) source(key string, isBool bool) (string, bool) {
 		return val.Value, found
 	}
 
-	// bools are defaulted to true if the flag was present.
+	// bools are defaulted to true if the flag was present. A flag that wasn't
+	// known to be a bool when the arguments were split, such as a map entry
+	// added by a parser, may have taken a positional argument, so give it back.
 	if val.Value != "" {
 		f.args = append([]string{val.Value}, f.args...)
 	}
@@ -197,9 +499,72 @@ func (f *flag) source(key string, isBool bool) (string, bool) {
 	return "true", found
 }
 
-// Source implements the conf.sourcer interface. Returns the stringified value
+// Source implements the conf.Sourcer interface. Returns the stringified value
 // stored at the specified key from the flag source.
-func (f *flag) Source(fld Field) (string, bool) {
+func (f *flag) Source(fld Field) (string, bool, error) {
+	if fld.Options.Count {
+		return f.count(fld)
+	}
+
+	val, found := f.sourceField(fld)
+	if !fld.BoolField {
+		return val, found, nil
+	}
+
+	// A bool can be turned off with --no-<flag>, unless another field
+	// already uses that name. The flag given last wins.
+	long := strings.ToLower(strings.Join(fld.FlagKey, `-`))
+	neg := negatePrefix + long
+	if _, taken := f.spec.long[neg]; taken {
+		return val, found, nil
+	}
+
+	nval, negated := f.source(neg, true)
+	if !negated || (found && f.last(long, string(fld.Options.ShortFlagChar)) > f.last(neg)) {
+		return val, found, nil
+	}
+
+	b, err := strconv.ParseBool(nval)
+	if err != nil {
+		return "", false, fmt.Errorf("flag --%s: invalid value %q", neg, nval)
+	}
+	return strconv.FormatBool(!b), true, nil
+}
+
+// count returns the number of times the field's flags were given. A flag
+// given with a value, as in --verbose=2, counts that many times.
+func (f *flag) count(fld Field) (string, bool, error) {
+	keys := []string{strings.ToLower(strings.Join(fld.FlagKey, `-`))}
+	if fld.Options.ShortFlagChar != 0 {
+		keys = append(keys, strings.ToLower(string(fld.Options.ShortFlagChar)))
+	}
+
+	var total int
+	var found bool
+	for _, k := range keys {
+		for _, val := range f.m[k] {
+			found = true
+			f.consumed[k] = true
+
+			if !val.HasValue {
+				total++
+				continue
+			}
+
+			n, err := strconv.Atoi(val.Value)
+			if err != nil {
+				return "", false, fmt.Errorf("flag --%s: invalid count %q", keys[0], val.Value)
+			}
+			total += n
+		}
+	}
+
+	return strconv.Itoa(total), found, nil
+}
+
+// sourceField returns the value of the field's short flag or, when it
+// wasn't given, of the long flag.
+func (f *flag) sourceField(fld Field) (string, bool) {
 	if fld.Options.ShortFlagChar != 0 {
 		if val, found := f.source(string(fld.Options.ShortFlagChar), fld.BoolField); found {
 			return val, found
@@ -209,6 +574,50 @@ func (f *flag) Source(fld Field) (string, bool) {
 	return f.source(strings.Join(fld.FlagKey, `-`), fld.BoolField)
 }
 
+// last returns the position of the last of the flags given, or -1 when
+// none of them were.
+func (f *flag) last(keys ...string) int {
+	pos := -1
+	for _, key := range keys {
+		if vals := f.m[strings.ToLower(key)]; len(vals) > 0 {
+			pos = max(pos, vals[len(vals)-1].pos)
+		}
+	}
+	return pos
+}
+
+// sourceAll returns every value given for the field in the order given,
+// with the values of the short and long flag combined. It is used to fill
+// slice and map fields from repeated flags.
+func (f *flag) sourceAll(fld Field) ([]string, bool) {
+	keys := []string{strings.ToLower(strings.Join(fld.FlagKey, `-`))}
+	if fld.Options.ShortFlagChar != 0 {
+		keys = append(keys, strings.ToLower(string(fld.Options.ShortFlagChar)))
+	}
+
+	var vals []flagValue
+	for _, k := range keys {
+		if _, found := f.m[k]; found {
+			f.consumed[k] = true
+			vals = append(vals, f.m[k]...)
+		}
+	}
+
+	if len(vals) == 0 {
+		return nil, false
+	}
+
+	slices.SortFunc(vals, func(a, b flagValue) int {
+		return a.pos - b.pos
+	})
+
+	values := make([]string, len(vals))
+	for i, val := range vals {
+		values[i] = val.Value
+	}
+	return values, true
+}
+
 // unconsumedFlags returns a list of flags that were parsed but never consumed by any field.
 func (f *flag) unconsumedFlags() []string {
 	var unconsumed []string
@@ -220,6 +629,9 @@ func (f *flag) unconsumedFlags() []string {
 	return unconsumed
 }
 
+// negatePrefix turns off a bool flag when added to its name.
+const negatePrefix = "no-"
+
 // flagUsage constructs a usage string for the flag argument.
 func flagUsage(fld Field) string {
 	usage := "    "
@@ -228,9 +640,20 @@ func flagUsage(fld Field) string {
 		usage = "-" + strings.ToLower(string(fld.Options.ShortFlagChar)) + ", "
 	}
 
-	usage += "--" + strings.ToLower(strings.Join(fld.FlagKey, `-`))
+	// The help and version flags may only have a short form.
+	long := strings.ToLower(strings.Join(fld.FlagKey, `-`))
+	if long == "" {
+		return strings.TrimSuffix(usage, ", ")
+	}
+
+	usage += "--"
+
+	// Bools can be negated, except for the help and version flags.
+	if fld.BoolField && fld.Name != helpKey && fld.Name != versionKey {
+		usage += "[" + negatePrefix + "]"
+	}
 
-	return usage
+	return usage + long
 }
 
 // longOptInfo constructs a long option description string.
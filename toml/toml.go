@@ -0,0 +1,80 @@
+// Package toml provides toml support for conf.
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/BurntSushi/toml"
+)
+
+// TOML provides support for unmarshalling TOML into the applications
+// config value. After the toml is unmarshalled, the Parse function is
+// executed to apply defaults and overrides. Fields that are not set to
+// their zero after the toml is parsed will have the defaults ignored.
+type TOML struct {
+	data    []byte
+	path    string
+	readErr error
+}
+
+// WithData accepts the toml document as a slice of bytes.
+func WithData(data []byte) TOML {
+	return TOML{
+		data: data,
+	}
+}
+
+// WithReader accepts a reader to read the toml. A failure to read is
+// reported when the config is parsed.
+func WithReader(r io.Reader) TOML {
+	var b bytes.Buffer
+	if _, err := b.ReadFrom(r); err != nil {
+		return TOML{readErr: err}
+	}
+
+	return WithData(b.Bytes())
+}
+
+// WithFile accepts the path to a toml file. The file is read every time
+// the config is parsed, so a conf.Reloader picks up changes to it.
+func WithFile(path string) TOML {
+	return TOML{
+		path: path,
+	}
+}
+
+// Name implements the conf.Namer interface.
+func (t TOML) Name() string {
+	return "toml"
+}
+
+// Files implements the conf.FileBacked interface.
+func (t TOML) Files() []string {
+	if t.path == "" {
+		return nil
+	}
+	return []string{t.path}
+}
+
+// Process performs the actual processing of the toml.
+func (t TOML) Process(prefix string, cfg any) error {
+	if t.readErr != nil {
+		return fmt.Errorf("read toml: %w", t.readErr)
+	}
+
+	data := t.data
+	if t.path != "" {
+		var err error
+		if data, err = os.ReadFile(t.path); err != nil {
+			return fmt.Errorf("read toml: %w", err)
+		}
+	}
+
+	if err := toml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("unmarshal toml: %w", err)
+	}
+	return nil
+}
@@ -17,6 +17,63 @@ const (
 	versionKey = "version"
 )
 
+// builtinFlag is a flag that stops the parse to show information, such as
+// the help message.
+type builtinFlag struct {
+	name  string
+	short rune
+	set   bool
+}
+
+// enabled reports if the flag can be given in any form.
+func (bf builtinFlag) enabled() bool {
+	return bf.name != "" || bf.short != 0
+}
+
+// is reports if the name given on the command line is the flag.
+func (bf builtinFlag) is(name string) bool {
+	if bf.name != "" && strings.EqualFold(name, bf.name) {
+		return true
+	}
+	return bf.short != 0 && name == string(bf.short)
+}
+
+// WithHelpFlag returns a ParseOption that changes the names of the flag
+// that asks for the help message, which are --help and -h by default. An
+// empty name or a zero short leaves that form out, so a field can take
+// the name, and -? keeps working while either form is left.
+func WithHelpFlag(name string, short rune) ParseOption {
+	return func(opts *parseOptions) {
+		opts.help = builtinFlag{name: strings.ToLower(name), short: short, set: true}
+	}
+}
+
+// WithVersionFlag returns a ParseOption that changes the names of the flag
+// that asks for the version, which are --version and -v by default. An
+// empty name or a zero short leaves that form out, so a field can take
+// the name, such as a verbosity counter taking -v.
+func WithVersionFlag(name string, short rune) ParseOption {
+	return func(opts *parseOptions) {
+		opts.version = builtinFlag{name: strings.ToLower(name), short: short, set: true}
+	}
+}
+
+// helpFlag returns the names of the help flag.
+func (opts *parseOptions) helpFlag() builtinFlag {
+	if !opts.help.set {
+		return builtinFlag{name: helpKey, short: 'h'}
+	}
+	return opts.help
+}
+
+// versionFlag returns the names of the version flag.
+func (opts *parseOptions) versionFlag() builtinFlag {
+	if !opts.version.set {
+		return builtinFlag{name: versionKey, short: 'v'}
+	}
+	return opts.version
+}
+
 type sortedFields struct {
 	fields []Field
 }
@@ -45,27 +102,33 @@ func containsField(fields []Field, name string) bool {
 	return false
 }
 
-func fmtUsage(namespace string, fields []Field) string {
+func fmtUsage(namespace string, fields []Field, opts *parseOptions) string {
 	var sb strings.Builder
 
-	fields = append(fields, Field{
-		Name:      "help",
-		BoolField: true,
-		Field:     reflect.ValueOf(true),
-		FlagKey:   []string{"help"},
-		Options: FieldOptions{
-			ShortFlagChar: 'h',
-			Help:          "display this help message",
-		}})
-
-	if containsField(fields, buildKey) {
+	if opts.configFile {
+		fields = append(fields, opts.configUsage())
+	}
+
+	if help := opts.helpFlag(); help.enabled() {
+		fields = append(fields, Field{
+			Name:      "help",
+			BoolField: true,
+			Field:     reflect.ValueOf(true),
+			FlagKey:   []string{help.name},
+			Options: FieldOptions{
+				ShortFlagChar: help.short,
+				Help:          "display this help message",
+			}})
+	}
+
+	if version := opts.versionFlag(); version.enabled() && containsField(fields, buildKey) {
 		fields = append(fields, Field{
 			Name:      "version",
 			BoolField: true,
 			Field:     reflect.ValueOf(true),
-			FlagKey:   []string{"version"},
+			FlagKey:   []string{version.name},
 			Options: FieldOptions{
-				ShortFlagChar: 'v',
+				ShortFlagChar: version.short,
 				Help:          "display version",
 			}})
 	}
@@ -74,20 +137,45 @@ func fmtUsage(namespace string, fields []Field) string {
 	sort.Sort(&sf)
 
 	_, file := path.Split(os.Args[0])
-	fmt.Fprintf(&sb, "Usage: %s [options...] [arguments...]\n\n", file)
+	if opts.command != "" {
+		file += " " + opts.command
+	}
 
 	w := new(tabwriter.Writer)
 	w.Init(&sb, 0, 4, 2, ' ', tabwriter.TabIndent)
 
+	if len(opts.commands) > 0 {
+		fmt.Fprintf(&sb, "Usage: %s [options...] <command> [arguments...]\n\n", file)
+
+		fmt.Fprintln(&sb, "COMMANDS")
+		writeCommands(w, opts.commands)
+	} else {
+		fmt.Fprintf(&sb, "Usage: %s [options...] [arguments...]\n\n", file)
+	}
+
 	fmt.Fprintln(&sb, "OPTIONS")
 	writeOptions(w, sf.fields)
 
+	if len(opts.globals) > 0 {
+		fmt.Fprintln(&sb, "GLOBAL OPTIONS")
+		writeOptions(w, opts.globals)
+	}
+
 	fmt.Fprintln(&sb, "ENVIRONMENT")
 	writeEnv(w, namespace, sf.fields)
 
 	return sb.String()
 }
 
+func writeCommands(w *tabwriter.Writer, commands []command) {
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s\t%s\n", cmd.name, cmd.help)
+	}
+
+	fmt.Fprint(w, "\n")
+	w.Flush()
+}
+
 func writeOptions(w *tabwriter.Writer, fields []Field) {
 	for _, fld := range fields {
 
@@ -152,6 +240,9 @@ func writeEnv(w *tabwriter.Writer, namespace string, fields []Field) {
 	}
 
 	w.Flush()
+
+	fmt.Fprintf(w, "\n  Add the %s suffix to any variable to read its value from a file.\n", fileSuffix)
+	w.Flush()
 }
 
 // getTypeAndHelp extracts the type and help message for a single field for
@@ -243,6 +334,30 @@ func getOptString(fld Field) string {
 	if fld.Options.Immutable {
 		opts = append(opts, "immutable")
 	}
+	if fld.Options.Count {
+		opts = append(opts, "count")
+	}
+	if fld.Options.Min != "" {
+		opts = append(opts, fmt.Sprintf("min: %s", fld.Options.Min))
+	}
+	if fld.Options.Max != "" {
+		opts = append(opts, fmt.Sprintf("max: %s", fld.Options.Max))
+	}
+	if fld.Options.MinLen > 0 {
+		opts = append(opts, fmt.Sprintf("minlen: %d", fld.Options.MinLen))
+	}
+	if fld.Options.MaxLen > 0 {
+		opts = append(opts, fmt.Sprintf("maxlen: %d", fld.Options.MaxLen))
+	}
+	if len(fld.Options.OneOf) > 0 {
+		opts = append(opts, fmt.Sprintf("oneof: %s", strings.Join(fld.Options.OneOf, "|")))
+	}
+	if fld.Options.Pattern != "" {
+		opts = append(opts, fmt.Sprintf("pattern: %s", fld.Options.Pattern))
+	}
+	if fld.Options.File != "" {
+		opts = append(opts, fmt.Sprintf("file: %s", fld.Options.File))
+	}
 	if fld.Options.Mask {
 		fld.Options.DefaultVal = maskVal(fld.Options.DefaultVal)
 	}
@@ -0,0 +1,280 @@
+package conf
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// durationT is used to compare time.Duration fields as durations.
+var durationT = reflect.TypeFor[time.Duration]()
+
+// A ValidationError occurs when the final value of a field breaks one of
+// its validation tags: min, max, minlen, maxlen, oneof or pattern.
+type ValidationError struct {
+	Field   string // The name of the struct field.
+	Key     string // The path to the field, such as Web.APIHost.
+	EnvKey  string // The environment variable that sets the field.
+	FlagKey string // The long flag that sets the field, without dashes.
+	Source  string // The source that set the value, empty if none did.
+	Value   string // The value of the field, masked for mask and noprint.
+	Rule    string // The tag that failed, such as max.
+	Param   string // The value of the tag, such as 65535.
+	reason  string
+}
+
+func (err *ValidationError) Error() string {
+	return fmt.Sprintf("invalid value '%s' for field %s: %s", err.Value, err.Field, err.reason)
+}
+
+// checkRules verifies the validation tags can be applied to the type.
+func checkRules(opts FieldOptions, typ reflect.Type) error {
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	for _, bound := range []struct{ rule, param string }{{"min", opts.Min}, {"max", opts.Max}} {
+		if bound.param == "" {
+			continue
+		}
+		if _, err := compareNumber(reflect.Zero(typ), bound.param); err != nil {
+			return fmt.Errorf("%s value %q can't be applied to type %s: %w", bound.rule, bound.param, typ, err)
+		}
+	}
+
+	if opts.MinLen > 0 || opts.MaxLen > 0 {
+		switch typ.Kind() {
+		case reflect.String, reflect.Slice, reflect.Map:
+		default:
+			return fmt.Errorf("minlen and maxlen can't be applied to type %s", typ)
+		}
+	}
+
+	if opts.Pattern != "" && typ.Kind() != reflect.String {
+		return fmt.Errorf("pattern can't be applied to type %s", typ)
+	}
+
+	if opts.Count {
+		var integer bool
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			integer = typ != durationT
+		}
+		if !integer {
+			return fmt.Errorf("count can't be applied to type %s", typ)
+		}
+	}
+
+	return nil
+}
+
+// compareNumber compares the number held by the value with the bound,
+// which is parsed as the type of the value. Integers and durations are
+// compared as integers so values above 2^53 keep their precision.
+func compareNumber(v reflect.Value, bound string) (int, error) {
+	if v.Type() == durationT {
+		d, err := time.ParseDuration(bound)
+		return cmp.Compare(v.Int(), int64(d)), err
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(bound, 0, 64)
+		return cmp.Compare(v.Int(), n), err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(bound, 0, 64)
+		return cmp.Compare(v.Uint(), n), err
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(bound, 64)
+		return cmp.Compare(v.Float(), n), err
+	}
+
+	return 0, fmt.Errorf("not a number or duration")
+}
+
+// validate checks the final value of the field against its validation
+// tags. It returns the tag that failed and the reason, or an empty rule
+// if the value is valid. A nil pointer has no value to check.
+func validate(field Field) (rule string, param string, reason string) {
+	opts := field.Options
+	v := field.Field
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", "", ""
+		}
+		v = v.Elem()
+	}
+
+	if c, err := compareNumber(v, opts.Min); opts.Min != "" && err == nil && c < 0 {
+		return "min", opts.Min, "must be at least " + opts.Min
+	}
+	if c, err := compareNumber(v, opts.Max); opts.Max != "" && err == nil && c > 0 {
+		return "max", opts.Max, "must be at most " + opts.Max
+	}
+
+	if opts.MinLen > 0 || opts.MaxLen > 0 {
+		var n int
+		switch v.Kind() {
+		case reflect.String:
+			n = utf8.RuneCountInString(v.String())
+		case reflect.Slice, reflect.Map:
+			n = v.Len()
+		}
+
+		if n < opts.MinLen {
+			return "minlen", strconv.Itoa(opts.MinLen), fmt.Sprintf("must have a length of at least %d", opts.MinLen)
+		}
+		if opts.MaxLen > 0 && n > opts.MaxLen {
+			return "maxlen", strconv.Itoa(opts.MaxLen), fmt.Sprintf("must have a length of at most %d", opts.MaxLen)
+		}
+	}
+
+	if len(opts.OneOf) > 0 {
+		values := []reflect.Value{v}
+		if v.Kind() == reflect.Slice {
+			values = values[:0]
+			for i := range v.Len() {
+				values = append(values, v.Index(i))
+			}
+		}
+
+		param := strings.Join(opts.OneOf, "|")
+		for _, value := range values {
+			if !slices.Contains(opts.OneOf, fieldString(value)) {
+				return "oneof", param, "must be one of " + param
+			}
+		}
+	}
+
+	if opts.pattern != nil {
+		if !opts.pattern.MatchString(v.String()) {
+			return "pattern", opts.Pattern, "must match " + opts.Pattern
+		}
+	}
+
+	return "", "", ""
+}
+
+// =============================================================================
+
+// validator is implemented by config structs that check rules involving
+// more than one field.
+type validator interface {
+	Validate() error
+}
+
+// structValidator is a Validate hook found in the config struct.
+type structValidator struct {
+	key string
+	v   validator
+}
+
+// findValidators returns the Validate hooks of the struct at the key path
+// and every struct nested in it, deepest first. The hook of an embedded
+// struct is skipped when it is promoted to the struct that embeds it, so
+// it runs once. When the outer struct declares its own Validate method
+// the embedded one is shadowed and both are called.
+func findValidators(key string, s reflect.Value) []structValidator {
+	var found []structValidator
+
+	t := s.Type()
+	outer := validatorFrom(s)
+	promoted := outer != nil && !declaresValidate(t)
+
+	for i := 0; i < s.NumField(); i++ {
+		f := s.Field(i)
+		structField := t.Field(i)
+
+		if !f.CanSet() || structField.Tag.Get("conf") == "-" {
+			continue
+		}
+
+		for f.Kind() == reflect.Pointer && !f.IsNil() {
+			f = f.Elem()
+		}
+
+		if f.Kind() != reflect.Struct || setterFrom(f) != nil || textUnmarshaler(f) != nil || binaryUnmarshaler(f) != nil {
+			continue
+		}
+
+		fieldKey := structField.Name
+		if key != "" {
+			fieldKey = key + "." + fieldKey
+		}
+
+		inner := findValidators(fieldKey, f)
+		if structField.Anonymous && promoted {
+			inner = filterValidator(inner, fieldKey)
+		}
+		found = append(found, inner...)
+	}
+
+	if outer != nil {
+		found = append(found, structValidator{key: key, v: outer})
+	}
+
+	return found
+}
+
+// autogenerated is the file the gc toolchain reports for the code of the
+// methods it generates, such as the ones promoted from an embedded field.
+const autogenerated = "<autogenerated>"
+
+// declaresValidate reports if the type, or a pointer to it, declares its
+// own Validate method rather than having one promoted from an embedded
+// field. Reflection can't tell the two apart, so this relies on an
+// implementation detail of the gc toolchain: the promoted method is code
+// it generates, which has no source file. TestValidateHookEmbedded fails
+// if the toolchain stops reporting it that way.
+func declaresValidate(t reflect.Type) bool {
+	for _, typ := range []reflect.Type{t, reflect.PointerTo(t)} {
+		m, ok := typ.MethodByName("Validate")
+		if !ok {
+			continue
+		}
+
+		pc := m.Func.Pointer()
+		file, _ := runtime.FuncForPC(pc).FileLine(pc)
+		return file != autogenerated
+	}
+
+	return false
+}
+
+// filterValidator removes the validator for the struct at the key path.
+func filterValidator(vs []structValidator, key string) []structValidator {
+	return slices.DeleteFunc(vs, func(sv structValidator) bool { return sv.key == key })
+}
+
+// validatorFrom returns the Validate hook of the struct, if it has one.
+func validatorFrom(field reflect.Value) (v validator) {
+	interfaceFrom(field, func(i any, ok *bool) { v, *ok = i.(validator) })
+	return v
+}
+
+// runValidators calls the Validate hook of every struct in the config,
+// deepest first, reporting each failure with the key path of its struct.
+func runValidators(cfgStruct any, errs *collector) error {
+	for _, sv := range findValidators("", reflect.ValueOf(cfgStruct).Elem()) {
+		err := sv.v.Validate()
+		if err == nil {
+			continue
+		}
+
+		if sv.key != "" {
+			err = fmt.Errorf("%s: %w", sv.key, err)
+		}
+		if err := errs.report(err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package yaml
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Layered deep-merges several yaml documents, in order, before they are
+// unmarshalled into the applications config value. Later documents are
+// overlays on earlier ones:
+//
+//   - Mappings are merged key by key, recursively.
+//   - Scalars replace the earlier value.
+//   - Sequences replace the earlier sequence, unless AppendSlices is used,
+//     in which case they are appended to it.
+//   - An explicit null (key: null or key: ~) removes the key, so the field
+//     falls back to its default. This holds for mappings inside sequences
+//     too, and a document that is just a null contributes nothing.
+//   - A value of a different kind, such as a scalar over a mapping,
+//     replaces the earlier value.
+//
+// Anchors and aliases are resolved within each document before merging.
+type Layered struct {
+	layers       []YAML
+	appendSlices bool
+}
+
+// WithLayers accepts the yaml documents to merge, from lowest to highest
+// precedence, such as a base file followed by environment overlays.
+func WithLayers(layers ...YAML) Layered {
+	return Layered{
+		layers: layers,
+	}
+}
+
+// AppendSlices returns a copy of the layered documents where sequences are
+// appended to the sequences of earlier documents instead of replacing them.
+func (l Layered) AppendSlices() Layered {
+	l.appendSlices = true
+	return l
+}
+
+// Name implements the conf.Namer interface.
+func (l Layered) Name() string {
+	return "yaml"
+}
+
+// Files implements the conf.FileBacked interface.
+func (l Layered) Files() []string {
+	var files []string
+	for _, layer := range l.layers {
+		files = append(files, layer.Files()...)
+	}
+	return files
+}
+
+// Process performs the actual processing of the yaml.
+func (l Layered) Process(prefix string, cfg any) error {
+	var merged *yaml.Node
+
+	for _, layer := range l.layers {
+		data, err := layer.read()
+		if err != nil {
+			return err
+		}
+
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("unmarshal yaml: %w", err)
+		}
+
+		// An empty document has nothing to contribute.
+		if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+			continue
+		}
+
+		// Nor does a document that is just an explicit null.
+		root := expandAliases(doc.Content[0])
+		if isNull(root) {
+			continue
+		}
+
+		if merged == nil {
+			merged = removeNulls(root)
+			continue
+		}
+		merged = l.merge(merged, root)
+	}
+
+	if merged == nil {
+		return nil
+	}
+
+	if err := merged.Decode(cfg); err != nil {
+		return fmt.Errorf("unmarshal yaml: %w", err)
+	}
+	return nil
+}
+
+// merge applies the overlay node on top of the base node.
+func (l Layered) merge(base *yaml.Node, overlay *yaml.Node) *yaml.Node {
+	switch {
+	case base.Kind == yaml.MappingNode && overlay.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(overlay.Content); i += 2 {
+			key, value := overlay.Content[i], overlay.Content[i+1]
+
+			idx := -1
+			for j := 0; j+1 < len(base.Content); j += 2 {
+				if base.Content[j].Value == key.Value {
+					idx = j
+					break
+				}
+			}
+
+			switch {
+			case isNull(value):
+				if idx >= 0 {
+					base.Content = append(base.Content[:idx], base.Content[idx+2:]...)
+				}
+			case idx >= 0:
+				base.Content[idx+1] = l.merge(base.Content[idx+1], value)
+			default:
+				base.Content = append(base.Content, key, removeNulls(value))
+			}
+		}
+		return base
+
+	case base.Kind == yaml.SequenceNode && overlay.Kind == yaml.SequenceNode && l.appendSlices:
+		for _, item := range overlay.Content {
+			base.Content = append(base.Content, removeNulls(item))
+		}
+		return base
+	}
+
+	return removeNulls(overlay)
+}
+
+// isNull reports if the node is an explicit null.
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null"
+}
+
+// removeNulls drops mapping keys set to null, since a null in any layer
+// means the key is not set. Mappings inside sequences are cleaned as well,
+// while a null element of a sequence is kept as an element.
+func removeNulls(n *yaml.Node) *yaml.Node {
+	switch n.Kind {
+	case yaml.MappingNode:
+		content := n.Content[:0]
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if isNull(n.Content[i+1]) {
+				continue
+			}
+			content = append(content, n.Content[i], removeNulls(n.Content[i+1]))
+		}
+		n.Content = content
+
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			n.Content[i] = removeNulls(item)
+		}
+	}
+	return n
+}
+
+// expandAliases replaces every alias with a copy of the node it refers to,
+// so the node can be merged with nodes from other documents.
+func expandAliases(n *yaml.Node) *yaml.Node {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+
+	c := *n
+	c.Anchor = ""
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = expandAliases(child)
+	}
+	return &c
+}
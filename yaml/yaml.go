@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"os"
 
 	"gopkg.in/yaml.v3"
 )
@@ -15,6 +16,7 @@ import (
 // their zero after the yaml is parsed will have the defaults ignored.
 type YAML struct {
 	data []byte
+	path string
 }
 
 // WithData accepts the yaml document as a slice of bytes.
@@ -34,11 +36,51 @@ func WithReader(r io.Reader) YAML {
 	return WithData(b.Bytes())
 }
 
+// WithFile accepts the path to a yaml file. The file is read every time
+// the config is parsed, so a conf.Reloader picks up changes to it.
+func WithFile(path string) YAML {
+	return YAML{
+		path: path,
+	}
+}
+
+// Name implements the conf.Namer interface.
+func (y YAML) Name() string {
+	return "yaml"
+}
+
+// Files implements the conf.FileBacked interface.
+func (y YAML) Files() []string {
+	if y.path == "" {
+		return nil
+	}
+	return []string{y.path}
+}
+
 // Process performs the actual processing of the yaml.
 func (y YAML) Process(prefix string, cfg any) error {
-	err := yaml.Unmarshal(y.data, cfg)
+	data, err := y.read()
+	if err != nil {
+		return err
+	}
+
+	err = yaml.Unmarshal(data, cfg)
 	if err != nil {
 		return fmt.Errorf("unmarshal yaml: %w", err)
 	}
 	return nil
 }
+
+// read returns the yaml document, reading it from the file if one was
+// provided.
+func (y YAML) read() ([]byte, error) {
+	if y.path == "" {
+		return y.data, nil
+	}
+
+	data, err := os.ReadFile(y.path)
+	if err != nil {
+		return nil, fmt.Errorf("read yaml: %w", err)
+	}
+	return data, nil
+}